@@ -0,0 +1,22 @@
+// Package api machine-readable contract of the http api
+package api
+
+import (
+	_ "embed"
+
+	swaggerfiles "github.com/swaggo/files/v2"
+)
+
+// OpenAPI OpenAPI 3.1 document of the http api in yaml
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// Docs page rendering the OpenAPI document served next to it
+//
+//go:embed docs.html
+var Docs []byte
+
+// DocsAssets swagger-ui 5.18.2 build loaded by the docs page, embedded by
+// github.com/swaggo/files/v2 and pinned through go.sum
+var DocsAssets = swaggerfiles.FS
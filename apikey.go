@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/trevatk/go-template/internal/auth"
+	"github.com/trevatk/go-template/internal/config"
+	"github.com/trevatk/go-template/internal/db"
+	"github.com/trevatk/go-template/internal/domain"
+)
+
+// apiKeyUsage usage of the apikey command
+const apiKeyUsage = `usage:
+  apikey create --tenant <id> --name <name> [--scope <scope>]... [--expires <duration>] [-- config flags]
+  apikey list --tenant <id> [-- config flags]
+  apikey revoke --tenant <id> --id <key id> [-- config flags]`
+
+// scopeList repeatable --scope flag
+type scopeList []string
+
+// String implement flag.Value
+func (s *scopeList) String() string {
+	return strings.Join(*s, " ")
+}
+
+// Set implement flag.Value
+func (s *scopeList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// apiKey manage api keys of a tenant directly in the database, e.g. to issue the first key
+// of a tenant. Flags after -- configure the service as for the server itself.
+func apiKey(args []string) {
+
+	if len(args) == 0 {
+		log.Fatal(apiKeyUsage)
+	}
+
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	tenantID := fs.String("tenant", "", "tenant of the api key")
+	name := fs.String("name", "", "name of the new api key")
+	expires := fs.Duration("expires", 0, "lifetime of the new api key, unlimited if unset")
+	id := fs.Int64("id", 0, "id of the api key to revoke")
+
+	var scopes scopeList
+	fs.Var(&scopes, "scope", "scope granted to the new api key, repeatable")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		log.Fatalf("invalid flags %v\n%s", err, apiKeyUsage)
+	}
+
+	if *tenantID == "" {
+		log.Fatalf("tenant is required\n%s", apiKeyUsage)
+	}
+
+	cfg, err := config.Load(fs.Args())
+	if err != nil {
+		log.Fatalf("invalid configuration\n%v", err)
+	}
+
+	sqlite, err := db.NewSQLite(&cfg.SQLite)
+	if err != nil {
+		log.Fatalf("failed to open database %v", err)
+	}
+	defer func() { _ = sqlite.Close() }()
+
+	if err := db.Migrate(sqlite, cfg.SQLite.MigrationsDir); err != nil {
+		log.Fatalf("failed to execute database migration %v", err)
+	}
+
+	// operators of the command may issue any scope a role grants within the tenant
+	ctx := domain.WithTenant(context.Background(), *tenantID)
+	ctx = domain.WithPrincipal(ctx, &domain.Principal{
+		Subject:  "cli",
+		TenantID: *tenantID,
+		Roles:    []string{domain.RoleAdmin, auth.RoleOperator},
+	})
+
+	service := domain.NewAPIKeyService(sqlite, auth.NewPolicy())
+
+	switch args[0] {
+	case "create":
+		err = createAPIKey(ctx, os.Stdout, service, *name, scopes, *expires)
+	case "list":
+		err = listAPIKeys(ctx, os.Stdout, service)
+	case "revoke":
+		err = revokeAPIKey(ctx, os.Stdout, service, *id)
+	default:
+		err = fmt.Errorf("unknown command %s\n%s", args[0], apiKeyUsage)
+	}
+
+	if err != nil {
+		_ = sqlite.Close()
+		log.Fatalf("%v", err)
+	}
+}
+
+func createAPIKey(ctx context.Context, w io.Writer, service *domain.APIKeyService, name string, scopes []string, expires time.Duration) error {
+
+	if name == "" {
+		return fmt.Errorf("name is required\n%s", apiKeyUsage)
+	}
+
+	newAPIKey := &domain.NewAPIKey{Name: name, Scopes: scopes}
+	if expires > 0 {
+		expiresAt := time.Now().Add(expires)
+		newAPIKey.ExpiresAt = &expiresAt
+	} else if expires < 0 {
+		return errors.New("expiry must be in the future")
+	}
+
+	created, err := service.Create(ctx, newAPIKey)
+	if err != nil {
+		return fmt.Errorf("failed to create api key %v", err)
+	}
+
+	// plaintext key is not stored and can not be shown again
+	_, err = fmt.Fprintf(w, "id: %d\nprefix: %s\nkey: %s\n", created.ID, created.Prefix, created.Key)
+
+	return err
+}
+
+func listAPIKeys(ctx context.Context, w io.Writer, service *domain.APIKeyService) error {
+
+	keys, err := service.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list api keys %v", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tSCOPES\tEXPIRES\tREVOKED")
+
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, " "), formatTime(k.ExpiresAt), formatTime(k.RevokedAt))
+	}
+
+	return tw.Flush()
+}
+
+func revokeAPIKey(ctx context.Context, w io.Writer, service *domain.APIKeyService, id int64) error {
+
+	if id <= 0 {
+		return fmt.Errorf("id is required\n%s", apiKeyUsage)
+	}
+
+	if err := service.Revoke(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke api key %v", err)
+	}
+
+	_, err := fmt.Fprintf(w, "revoked api key %d\n", id)
+
+	return err
+}
+
+// formatTime optional timestamp, - if unset
+func formatTime(t *time.Time) string {
+
+	if t == nil {
+		return "-"
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
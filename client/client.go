@@ -0,0 +1,295 @@
+// Package client typed http client of the person service
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tenantHeader         = "X-Tenant-ID"
+	idempotencyKeyHeader = "Idempotency-Key"
+	requestIDHeader      = "X-Request-ID"
+
+	// maxResponseSize largest response body read
+	maxResponseSize = 10 << 20
+)
+
+// Credentials authorize outgoing requests, client certificates are configured on the
+// transport of Config.HTTPClient instead
+type Credentials func(req *http.Request) error
+
+// BearerToken authorize requests with a static jwt
+func BearerToken(token string) Credentials {
+	return func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// TokenSource authorize requests with a jwt fetched for every attempt, e.g. to refresh
+// expiring tokens
+func TokenSource(token func(ctx context.Context) (string, error)) Credentials {
+	return func(req *http.Request) error {
+		t, err := token(req.Context())
+		if err != nil {
+			return fmt.Errorf("failed to fetch token %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+		return nil
+	}
+}
+
+// APIKey authorize requests with an api key issued by the admin endpoints
+func APIKey(key string) Credentials {
+	return func(req *http.Request) error {
+		req.Header.Set("Authorization", "ApiKey "+key)
+		return nil
+	}
+}
+
+// Config client configuration
+type Config struct {
+	// BaseURL scheme and host of the service, e.g. https://persons.example.com
+	BaseURL string
+	// Tenant sent with every request, may be empty, the service rejects tenants other than the
+	// tenant of the credentials unless they are granted tenant:cross
+	Tenant string
+	// Credentials authorization of every request, none if nil
+	Credentials Credentials
+	// Timeout of a single attempt including reading the response
+	Timeout time.Duration
+	// MaxRetries attempts repeated after failed ones, zero disables retries
+	MaxRetries int
+	// Backoff wait before the first retry, doubled for every further retry unless the
+	// service asks for a longer wait with Retry-After
+	Backoff time.Duration
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// DefaultConfig client defaults targeting baseURL
+func DefaultConfig(baseURL string) *Config {
+	return &Config{
+		BaseURL:    baseURL,
+		Timeout:    time.Second * 10,
+		MaxRetries: 3,
+		Backoff:    time.Millisecond * 100,
+	}
+}
+
+// Validate check configuration values
+func (c *Config) Validate() error {
+
+	var errs []error
+
+	if u, err := url.Parse(c.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("invalid base url %q", c.BaseURL))
+	}
+
+	if c.Timeout <= 0 {
+		errs = append(errs, errors.New("timeout must be positive"))
+	}
+
+	if c.MaxRetries < 0 || c.Backoff < 0 {
+		errs = append(errs, errors.New("retries and backoff must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// client sends requests to the service, shared by the typed clients
+type client struct {
+	cfg  *Config
+	http *http.Client
+	base string
+}
+
+// newClient create new client instance
+func newClient(cfg *Config) (*client, error) {
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &client{cfg: cfg, http: httpClient, base: strings.TrimSuffix(cfg.BaseURL, "/")}, nil
+}
+
+// request call to the service
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	// idempotencyKey makes retries of POST requests safe, the service replays the
+	// response of the first attempt
+	idempotencyKey string
+	// expected status of a successful response
+	expected int
+}
+
+// do send r, retrying failed attempts, and decode a successful response into out
+func (c *client) do(ctx context.Context, r *request, out interface{}) error {
+
+	var body []byte
+	if r.body != nil {
+		var err error
+		if body, err = json.Marshal(r.body); err != nil {
+			return fmt.Errorf("failed to encode request %v", err)
+		}
+	}
+
+	var lastErr error
+
+	// applied an earlier attempt may have been processed by the service before failing
+	applied := false
+
+	for attempt := 0; ; attempt++ {
+
+		status, header, data, err := c.attempt(ctx, r, body)
+		if err == nil && applied && r.method == http.MethodDelete && status == http.StatusNotFound {
+			// possibly deleted by the earlier attempt, the caller decides whether that suffices
+			return fmt.Errorf("%w: %w", ErrMaybeApplied, newError(status, header, data))
+		}
+
+		if err == nil && status == r.expected {
+			if out == nil {
+				return nil
+			}
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("failed to decode response %v", err)
+			}
+			return nil
+		}
+
+		// the caller gave up
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		wait := c.cfg.Backoff << attempt
+
+		if err != nil {
+			lastErr = err
+			applied = true
+		} else {
+			apiErr := newError(status, header, data)
+			if !retryable(status) {
+				return apiErr
+			}
+			lastErr = apiErr
+			applied = applied || status == http.StatusBadGateway || status == http.StatusGatewayTimeout
+
+			if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && time.Duration(seconds)*time.Second > wait {
+				wait = time.Duration(seconds) * time.Second
+			}
+		}
+
+		if attempt >= c.cfg.MaxRetries {
+			return lastErr
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt send r once, returning status, headers and body of the response
+func (c *client) attempt(ctx context.Context, r *request, body []byte) (int, http.Header, []byte, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	u := c.base + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, u, reader)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to create request %v", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.cfg.Tenant != "" {
+		req.Header.Set(tenantHeader, c.cfg.Tenant)
+	}
+	if r.idempotencyKey != "" {
+		req.Header.Set(idempotencyKeyHeader, r.idempotencyKey)
+	}
+
+	if c.cfg.Credentials != nil {
+		if err := c.cfg.Credentials(req); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to send request %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to read response %v", err)
+	}
+
+	return resp.StatusCode, resp.Header, data, nil
+}
+
+// retryable statuses of transient failures. Repeating a request is safe: reads and updates
+// are idempotent, creates carry an idempotency key and deletes finding the record gone after
+// an attempt that may have been processed succeed
+func retryable(status int) bool {
+	switch status {
+	case http.StatusConflict,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// newIdempotencyKey random key shared by all attempts of a request
+func newIdempotencyKey() (string, error) {
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key %v", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
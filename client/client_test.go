@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestClient person client of a stub service answering with handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *PersonClient {
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	cfg := DefaultConfig(srv.URL)
+	cfg.Tenant = "tenant-a"
+	cfg.Credentials = BearerToken("token")
+	cfg.Timeout = time.Millisecond * 200
+	cfg.Backoff = time.Millisecond
+
+	pc, err := NewPersonClient(cfg)
+	assert.NoError(t, err)
+
+	return pc
+}
+
+func TestRetries(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	var keys []string
+
+	// transient failures are retried with the same idempotency key
+	pc := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+
+		mu.Lock()
+		keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+		attempt := len(keys)
+		mu.Unlock()
+
+		assert.Equal("Bearer token", r.Header.Get("Authorization"))
+		assert.Equal("tenant-a", r.Header.Get(tenantHeader))
+
+		switch attempt {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			// exceeds the attempt timeout
+			time.Sleep(time.Millisecond * 300)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":7,"first_name":"retry"}`))
+		}
+	})
+
+	person, err := pc.Create(context.Background(), &NewPerson{FirstName: "retry", LastName: "client", Email: "retry@mailbox.com"})
+	assert.NoError(err)
+	assert.Equal(int64(7), person.ID)
+
+	if assert.Len(keys, 3) {
+		assert.NotEmpty(keys[0])
+		assert.Equal(keys[0], keys[1])
+		assert.Equal(keys[0], keys[2])
+	}
+
+	// retries are bounded
+	attempts := 0
+	pc = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	_, err = pc.Get(context.Background(), 1)
+	assert.ErrorIs(err, ErrServer)
+	assert.Equal(4, attempts)
+
+	// cancelled callers stop retrying
+	ctx, cancel := context.WithCancel(context.Background())
+	pc = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err = pc.Get(ctx, 1)
+	assert.ErrorIs(err, context.Canceled)
+}
+
+func TestRetriedDelete(t *testing.T) {
+
+	assert := assert.New(t)
+
+	// the first attempt deletes the record but its response is lost
+	var mu sync.Mutex
+	deleted := false
+
+	pc := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+
+		assert.Equal(http.MethodDelete, r.Method)
+
+		mu.Lock()
+		found := !deleted
+		deleted = true
+		mu.Unlock()
+
+		if !found {
+			http.Error(w, "person id does not exist", http.StatusNotFound)
+			return
+		}
+
+		time.Sleep(time.Millisecond * 300)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	// not found, the lost attempt may have deleted it
+	err := pc.Delete(context.Background(), 1)
+	assert.ErrorIs(err, ErrNotFound)
+	assert.ErrorIs(err, ErrMaybeApplied)
+
+	// records missing before any attempt was processed are not found
+	attempts := 0
+	pc = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		http.Error(w, "person id does not exist", http.StatusNotFound)
+	})
+
+	err = pc.Delete(context.Background(), 1)
+	assert.ErrorIs(err, ErrNotFound)
+	assert.NotErrorIs(err, ErrMaybeApplied)
+	assert.Equal(2, attempts)
+}
+
+func TestErrors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cases := []struct {
+		status      int
+		contentType string
+		body        string
+		expected    error
+		detail      string
+	}{
+		{
+			status:      http.StatusNotFound,
+			contentType: "text/plain; charset=utf-8",
+			body:        "person id does not exist\n",
+			expected:    ErrNotFound,
+			detail:      "person id does not exist",
+		},
+		{
+			status:      http.StatusForbidden,
+			contentType: "application/problem+json",
+			body:        `{"type":"about:blank","title":"Forbidden","status":403,"detail":"only the owner may modify this person"}`,
+			expected:    ErrForbidden,
+			detail:      "only the owner may modify this person",
+		},
+		{
+			status:      http.StatusBadRequest,
+			contentType: "application/problem+json",
+			body:        `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid request body: /id expected integer, but got string"}`,
+			expected:    ErrInvalid,
+			detail:      "invalid request body: /id expected integer, but got string",
+		},
+		{
+			status:      http.StatusUnauthorized,
+			contentType: "application/problem+json",
+			body:        `{"type":"about:blank","title":"Unauthorized","status":401}`,
+			expected:    ErrUnauthorized,
+		},
+	}
+
+	for _, c := range cases {
+
+		pc := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", c.contentType)
+			w.Header().Set(requestIDHeader, "request-1")
+			w.WriteHeader(c.status)
+			_, _ = w.Write([]byte(c.body))
+		})
+
+		err := pc.Delete(context.Background(), 1)
+		assert.ErrorIs(err, c.expected)
+
+		var apiErr *Error
+		if assert.True(errors.As(err, &apiErr)) {
+			assert.Equal(c.status, apiErr.StatusCode)
+			assert.Equal(c.detail, apiErr.Detail)
+			assert.Equal("request-1", apiErr.RequestID)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+
+	assert := assert.New(t)
+
+	_, err := NewPersonClient(DefaultConfig("persons.example.com"))
+	assert.Error(err)
+
+	cfg := DefaultConfig("https://persons.example.com")
+	cfg.Timeout = 0
+	cfg.MaxRetries = -1
+
+	_, err = NewPersonClient(cfg)
+	assert.ErrorContains(err, "timeout must be positive")
+	assert.ErrorContains(err, "must not be negative")
+}
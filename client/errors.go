@@ -0,0 +1,109 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// sentinel errors of failed calls, mirroring the errors of the service domain
+var (
+	// ErrInvalid request rejected as malformed
+	ErrInvalid = errors.New("invalid request")
+	// ErrUnauthorized missing or invalid credentials
+	ErrUnauthorized = errors.New("missing or invalid credentials")
+	// ErrForbidden missing scope or not the owner of the record
+	ErrForbidden = errors.New("operation not permitted")
+	// ErrNotFound record does not exist in the tenant
+	ErrNotFound = errors.New("resource id not found")
+	// ErrConflict request with the same idempotency key is in progress or differs
+	ErrConflict = errors.New("conflicting request")
+	// ErrRateLimited rate limit exceeded
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrServer service failed to process the request
+	ErrServer = errors.New("server error")
+	// ErrMaybeApplied an earlier attempt of the request may have been processed, e.g. a retried
+	// delete answered with not found because its first attempt deleted the record
+	ErrMaybeApplied = errors.New("an earlier attempt may have been applied")
+)
+
+// Error error response of the service, matches one of the sentinel errors with errors.Is
+type Error struct {
+	// StatusCode http status of the response
+	StatusCode int
+	// Title of problem details responses, status text otherwise
+	Title string
+	// Detail of problem details responses, plain text body otherwise
+	Detail string
+	// RequestID of the failed request, quoted when reporting problems
+	RequestID string
+}
+
+// problem RFC 7807 problem details document
+type problem struct {
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+// newError decode error response
+func newError(status int, header http.Header, body []byte) *Error {
+
+	e := &Error{
+		StatusCode: status,
+		Title:      http.StatusText(status),
+		RequestID:  header.Get(requestIDHeader),
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+
+	p := &problem{}
+	if mediaType == "application/problem+json" && json.Unmarshal(body, p) == nil {
+		if p.Title != "" {
+			e.Title = p.Title
+		}
+		e.Detail = p.Detail
+	} else if strings.HasPrefix(mediaType, "text/") {
+		e.Detail = strings.TrimSpace(string(body))
+	}
+
+	return e
+}
+
+// Error implement error
+func (e *Error) Error() string {
+
+	msg := fmt.Sprintf("%d %s", e.StatusCode, e.Title)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+
+	return msg
+}
+
+// Unwrap sentinel error of the status
+func (e *Error) Unwrap() error {
+
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict, e.StatusCode == http.StatusUnprocessableEntity:
+		return ErrConflict
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServer
+	default:
+		return ErrInvalid
+	}
+}
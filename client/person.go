@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const personPath = "/api/v1/person"
+
+// Person record owned by a principal of a tenant
+type Person struct {
+	ID       int64  `json:"id"`
+	TenantID string `json:"tenant_id"`
+	// Owner subject of the principal owning the record
+	Owner     string    `json:"owner"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt zero when never updated
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewPerson person to create, every field is required
+type NewPerson struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+}
+
+// UpdatePerson replacement of name and email of person ID
+type UpdatePerson struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+}
+
+// ListOptions paging of List
+type ListOptions struct {
+	// Limit persons per page, zero selects the service default
+	Limit int
+	// After cursor of the page, the NextAfter of the previous page
+	After int64
+}
+
+// PersonPage page of persons ordered by id
+type PersonPage struct {
+	Persons []*Person `json:"persons"`
+	// NextAfter cursor of the following page, zero on the last page
+	NextAfter int64 `json:"next_after"`
+}
+
+// PersonClient typed client of the person endpoints
+type PersonClient struct {
+	c *client
+}
+
+// NewPersonClient create new person client instance
+func NewPersonClient(cfg *Config) (*PersonClient, error) {
+
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PersonClient{c: c}, nil
+}
+
+// Create person owned by the caller, retries are deduplicated by an idempotency key
+func (pc *PersonClient) Create(ctx context.Context, person *NewPerson) (*Person, error) {
+
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return nil, err
+	}
+
+	created := &Person{}
+
+	err = pc.c.do(ctx, &request{
+		method:         http.MethodPost,
+		path:           personPath,
+		body:           person,
+		idempotencyKey: key,
+		expected:       http.StatusCreated,
+	}, created)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// Get person by id
+func (pc *PersonClient) Get(ctx context.Context, id int64) (*Person, error) {
+
+	person := &Person{}
+
+	err := pc.c.do(ctx, &request{
+		method:   http.MethodGet,
+		path:     fmt.Sprintf("%s/%d", personPath, id),
+		expected: http.StatusOK,
+	}, person)
+	if err != nil {
+		return nil, err
+	}
+
+	return person, nil
+}
+
+// Update name and email of a person, only the owner or admins may update
+func (pc *PersonClient) Update(ctx context.Context, person *UpdatePerson) (*Person, error) {
+
+	updated := &Person{}
+
+	err := pc.c.do(ctx, &request{
+		method:   http.MethodPut,
+		path:     personPath,
+		body:     person,
+		expected: http.StatusAccepted,
+	}, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// Delete person by id, only the owner or admins may delete. Not found after an attempt that may
+// have deleted the person matches both ErrNotFound and ErrMaybeApplied.
+func (pc *PersonClient) Delete(ctx context.Context, id int64) error {
+	return pc.c.do(ctx, &request{
+		method:   http.MethodDelete,
+		path:     fmt.Sprintf("%s/%d", personPath, id),
+		expected: http.StatusAccepted,
+	}, nil)
+}
+
+// List page of persons of the tenant, opts may be nil for the first page
+func (pc *PersonClient) List(ctx context.Context, opts *ListOptions) (*PersonPage, error) {
+
+	query := url.Values{}
+	if opts != nil {
+		if opts.Limit > 0 {
+			query.Set("limit", strconv.Itoa(opts.Limit))
+		}
+		if opts.After > 0 {
+			query.Set("after", strconv.FormatInt(opts.After, 10))
+		}
+	}
+
+	page := &PersonPage{}
+
+	err := pc.c.do(ctx, &request{
+		method:   http.MethodGet,
+		path:     personPath,
+		query:    query,
+		expected: http.StatusOK,
+	}, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
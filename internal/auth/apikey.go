@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/trevatk/go-template/internal/domain"
+)
+
+const (
+	// MethodAPIKey principal authenticated with an api key
+	MethodAPIKey = "apikey"
+)
+
+// APIKeyAuthenticator api key authenticator for machine clients
+type APIKeyAuthenticator struct {
+	apiKeyService *domain.APIKeyService
+}
+
+// NewAPIKeyAuthenticator create new api key authenticator instance
+func NewAPIKeyAuthenticator(apiKeyService *domain.APIKeyService) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{apiKeyService: apiKeyService}
+}
+
+// Scheme authentication scheme handled by authenticator
+func (a *APIKeyAuthenticator) Scheme() string {
+	return "ApiKey"
+}
+
+// Authenticate validate api key of request
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*domain.Principal, error) {
+
+	key, ok := credentials(r, a.Scheme())
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	apiKey, err := a.apiKeyService.Authenticate(r.Context(), key)
+	if err != nil {
+
+		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrKeyExpired) || errors.Is(err, domain.ErrKeyRevoked) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+		}
+
+		return nil, err
+	}
+
+	return &domain.Principal{
+		Subject:  "apikey:" + strconv.FormatInt(apiKey.ID, 10),
+		TenantID: apiKey.TenantID,
+		Scopes:   apiKey.Scopes,
+		Method:   MethodAPIKey,
+	}, nil
+}
@@ -0,0 +1,39 @@
+// Package auth caller authentication
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/trevatk/go-template/internal/domain"
+)
+
+var (
+	// ErrNoCredentials request does not carry credentials handled by the authenticator
+	ErrNoCredentials = errors.New("no credentials provided")
+	// ErrInvalidCredentials request credentials are malformed, expired or unknown
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator identify the caller of a request
+type Authenticator interface {
+	// Scheme authentication scheme advertised in WWW-Authenticate challenges
+	Scheme() string
+	// Authenticate return principal of request or ErrNoCredentials when the
+	// request does not use the authenticator scheme
+	Authenticate(r *http.Request) (*domain.Principal, error)
+}
+
+// credentials extract credentials from authorization header for scheme
+func credentials(r *http.Request, scheme string) (string, bool) {
+
+	header := r.Header.Get("Authorization")
+
+	prefix, value, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(prefix, scheme) {
+		return "", false
+	}
+
+	return strings.TrimSpace(value), true
+}
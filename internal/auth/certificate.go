@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/trevatk/go-template/internal/domain"
+)
+
+const (
+	// MethodCertificate principal authenticated with a verified tls client certificate
+	MethodCertificate = "mtls"
+)
+
+// CertificateAuthenticator tls client certificate authenticator for mutual tls clients. The
+// certificate subject maps onto the principal, common name is the subject, the first
+// organization the tenant and organizational units the roles.
+type CertificateAuthenticator struct{}
+
+// NewCertificateAuthenticator create new certificate authenticator instance
+func NewCertificateAuthenticator() *CertificateAuthenticator {
+	return &CertificateAuthenticator{}
+}
+
+// Scheme authentication scheme handled by authenticator
+func (a *CertificateAuthenticator) Scheme() string {
+	return "Certificate"
+}
+
+// Authenticate map verified client certificate of request onto a principal, certificates
+// are verified against the client ca bundle during the tls handshake
+func (a *CertificateAuthenticator) Authenticate(r *http.Request) (*domain.Principal, error) {
+
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	subject := r.TLS.VerifiedChains[0][0].Subject
+
+	if subject.CommonName == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	var tenant string
+	if len(subject.Organization) > 0 {
+		tenant = subject.Organization[0]
+	}
+
+	return &domain.Principal{
+		Subject:  "cert:" + subject.CommonName,
+		TenantID: tenant,
+		Roles:    subject.OrganizationalUnit,
+		Method:   MethodCertificate,
+	}, nil
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwk single JSON web key as defined by RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verificationKey key used to verify token signatures
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+// KeySet verification keys indexed by key id
+type KeySet struct {
+	keys map[string]*verificationKey
+}
+
+// LoadJWKS read JSON web key set from file
+func LoadJWKS(path string) (*KeySet, error) {
+
+	b, err := os.ReadFile(path) // #nosec G304 -- path is operator supplied configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks file %v", err)
+	}
+
+	var set struct {
+		Keys []*jwk `json:"keys"`
+	}
+
+	err = json.Unmarshal(b, &set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode jwks file %v", err)
+	}
+
+	if len(set.Keys) == 0 {
+		return nil, errors.New("jwks file contains no keys")
+	}
+
+	ks := &KeySet{keys: make(map[string]*verificationKey, len(set.Keys))}
+
+	for _, k := range set.Keys {
+
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		vk, err := parseJWK(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q %v", k.Kid, err)
+		}
+
+		if _, ok := ks.keys[k.Kid]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", k.Kid)
+		}
+
+		ks.keys[k.Kid] = vk
+	}
+
+	return ks, nil
+}
+
+// lookup find verification key for token header, a token without key id is
+// accepted only when the set holds a single key
+func (ks *KeySet) lookup(token *jwt.Token) (*verificationKey, error) {
+
+	kid, _ := token.Header["kid"].(string)
+
+	vk, ok := ks.keys[kid]
+	if !ok && kid == "" && len(ks.keys) == 1 {
+		for _, k := range ks.keys {
+			vk, ok = k, true
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if token.Method.Alg() != vk.alg {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	return vk, nil
+}
+
+func parseJWK(k *jwk) (*verificationKey, error) {
+
+	switch k.Kty {
+	case "oct":
+
+		secret, err := decodeSegment(k.K)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(secret) < 32 {
+			return nil, errors.New("symmetric key must be at least 256 bits")
+		}
+
+		return newVerificationKey(k.Alg, jwt.SigningMethodHS256.Alg(), secret)
+
+	case "RSA":
+
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() {
+			return nil, errors.New("rsa exponent out of range")
+		}
+
+		key := &rsa.PublicKey{N: n, E: int(e.Int64())}
+
+		return newVerificationKey(k.Alg, jwt.SigningMethodRS256.Alg(), key)
+
+	case "EC":
+
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("ec point is not on curve")
+		}
+
+		return newVerificationKey(k.Alg, jwt.SigningMethodES256.Alg(), key)
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func newVerificationKey(alg, expected string, key interface{}) (*verificationKey, error) {
+
+	if alg != "" && alg != expected {
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	return &verificationKey{alg: expected, key: key}, nil
+}
+
+func decodeSegment(s string) ([]byte, error) {
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64url value %v", err)
+	}
+
+	return b, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+
+	b, err := decodeSegment(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
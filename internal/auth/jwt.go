@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/trevatk/go-template/internal/domain"
+)
+
+const (
+	// MethodJWT principal authenticated with a bearer token
+	MethodJWT = "jwt"
+)
+
+// claims token claims mapped onto a principal
+type claims struct {
+	jwt.RegisteredClaims
+	Tenant string   `json:"tenant,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
+	Scope  string   `json:"scope,omitempty"`
+}
+
+// JWTVerifier bearer token authenticator
+type JWTVerifier struct {
+	keys   *KeySet
+	parser *jwt.Parser
+}
+
+// JWTConfig bearer token verification configuration
+type JWTConfig struct {
+	// JWKSFile path of the json web key set used to verify signatures
+	JWKSFile string `yaml:"jwks_file" env:"JWT_JWKS_FILE"`
+	// Issuer expected iss claim, not checked when empty
+	Issuer string `yaml:"issuer" env:"JWT_ISSUER"`
+	// Audience expected aud claim, not checked when empty
+	Audience string `yaml:"audience" env:"JWT_AUDIENCE"`
+	// Leeway tolerated clock skew when validating exp and nbf
+	Leeway time.Duration `yaml:"leeway" env:"JWT_LEEWAY"`
+}
+
+// DefaultJWTConfig jwt defaults, key set must be configured
+func DefaultJWTConfig() *JWTConfig {
+	return &JWTConfig{Leeway: time.Second * 30}
+}
+
+// Validate check configuration values
+func (c *JWTConfig) Validate() error {
+
+	var errs []error
+
+	if c.JWKSFile == "" {
+		errs = append(errs, errors.New("jwks file is required"))
+	}
+
+	if c.Leeway < 0 {
+		errs = append(errs, errors.New("leeway must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// NewJWTVerifier create new jwt verifier instance using keys of the configured key set
+func NewJWTVerifier(cfg *JWTConfig) (*JWTVerifier, error) {
+
+	if cfg.JWKSFile == "" {
+		return nil, errors.New("jwks file is unset")
+	}
+
+	keys, err := LoadJWKS(cfg.JWKSFile)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodHS256.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodES256.Alg(),
+		}),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &JWTVerifier{keys: keys, parser: jwt.NewParser(options...)}, nil
+}
+
+// Scheme authentication scheme handled by verifier
+func (v *JWTVerifier) Scheme() string {
+	return "Bearer"
+}
+
+// Authenticate validate bearer token of request
+func (v *JWTVerifier) Authenticate(r *http.Request) (*domain.Principal, error) {
+
+	token, ok := credentials(r, v.Scheme())
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	return v.Verify(token)
+}
+
+// Verify validate signed token and map its claims onto a principal
+func (v *JWTVerifier) Verify(token string) (*domain.Principal, error) {
+
+	var c claims
+
+	_, err := v.parser.ParseWithClaims(token, &c, func(t *jwt.Token) (interface{}, error) {
+
+		vk, err := v.keys.lookup(t)
+		if err != nil {
+			return nil, err
+		}
+
+		return vk.key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	// parser only validates expiry when present
+	if c.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: token has no expiry", ErrInvalidCredentials)
+	}
+
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	return &domain.Principal{
+		Subject:  c.Subject,
+		TenantID: c.Tenant,
+		Roles:    c.Roles,
+		Scopes:   strings.Fields(c.Scope),
+		Method:   MethodJWT,
+	}, nil
+}
@@ -0,0 +1,192 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/trevatk/go-template/internal/auth"
+)
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJWKS(t *testing.T, keys ...map[string]string) string {
+
+	b, err := json.Marshal(map[string]interface{}{"keys": keys})
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, b, 0600))
+
+	return path
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+
+	return signed
+}
+
+func TestJWTVerifier(t *testing.T) {
+
+	assert := assert.New(t)
+
+	secret := []byte("jwt-verifier-test-secret-0123456789")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(err)
+
+	path := writeJWKS(t,
+		map[string]string{"kty": "oct", "kid": "hs", "k": encode(secret)},
+		map[string]string{
+			"kty": "RSA",
+			"kid": "rs",
+			"n":   encode(rsaKey.N.Bytes()),
+			"e":   encode(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+		map[string]string{
+			"kty": "EC",
+			"kid": "es",
+			"crv": "P-256",
+			"x":   encode(ecKey.X.Bytes()),
+			"y":   encode(ecKey.Y.Bytes()),
+		},
+	)
+
+	cfg := auth.DefaultJWTConfig()
+	cfg.JWKSFile = path
+
+	verifier, err := auth.NewJWTVerifier(cfg)
+	assert.NoError(err)
+
+	valid := jwt.MapClaims{
+		"sub":    "subject",
+		"tenant": "tenant",
+		"roles":  []string{"admin"},
+		"scope":  "person:read person:write",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+
+	expired := jwt.MapClaims{
+		"sub": "subject",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	}
+
+	cases := []struct {
+		token string
+		valid bool
+	}{
+		{
+			// hs256 success
+			token: sign(t, jwt.SigningMethodHS256, "hs", secret, valid),
+			valid: true,
+		},
+		{
+			// rs256 success
+			token: sign(t, jwt.SigningMethodRS256, "rs", rsaKey, valid),
+			valid: true,
+		},
+		{
+			// es256 success
+			token: sign(t, jwt.SigningMethodES256, "es", ecKey, valid),
+			valid: true,
+		},
+		{
+			// expired
+			token: sign(t, jwt.SigningMethodRS256, "rs", rsaKey, expired),
+			valid: false,
+		},
+		{
+			// algorithm does not match key
+			token: sign(t, jwt.SigningMethodHS256, "rs", secret, valid),
+			valid: false,
+		},
+		{
+			// unknown key id
+			token: sign(t, jwt.SigningMethodHS256, "unknown", secret, valid),
+			valid: false,
+		},
+		{
+			// malformed
+			token: "malformed",
+			valid: false,
+		},
+	}
+
+	for _, c := range cases {
+
+		principal, err := verifier.Verify(c.token)
+		if !c.valid {
+			assert.ErrorIs(err, auth.ErrInvalidCredentials)
+			continue
+		}
+
+		assert.NoError(err)
+		assert.Equal("subject", principal.Subject)
+		assert.Equal("tenant", principal.TenantID)
+		assert.Equal([]string{"admin"}, principal.Roles)
+		assert.Equal([]string{"person:read", "person:write"}, principal.Scopes)
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cases := []struct {
+		key   map[string]string
+		valid bool
+	}{
+		{
+			// symmetric key too short
+			key:   map[string]string{"kty": "oct", "kid": "short", "k": encode([]byte("short"))},
+			valid: false,
+		},
+		{
+			// unsupported key type
+			key:   map[string]string{"kty": "OKP", "kid": "ed"},
+			valid: false,
+		},
+		{
+			// algorithm mismatch
+			key:   map[string]string{"kty": "oct", "kid": "hs", "alg": "RS256", "k": encode(make([]byte, 32))},
+			valid: false,
+		},
+		{
+			// success
+			key:   map[string]string{"kty": "oct", "kid": "hs", "alg": "HS256", "k": encode(make([]byte, 32))},
+			valid: true,
+		},
+	}
+
+	for _, c := range cases {
+
+		_, err := auth.LoadJWKS(writeJWKS(t, c.key))
+		if c.valid {
+			assert.NoError(err)
+		} else {
+			assert.Error(err)
+		}
+	}
+}
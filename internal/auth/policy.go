@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"github.com/trevatk/go-template/internal/domain"
+)
+
+const (
+	// ScopePersonRead read person records
+	ScopePersonRead = "person:read"
+	// ScopePersonWrite create and update person records
+	ScopePersonWrite = "person:write"
+	// ScopePersonDelete delete and erase person records
+	ScopePersonDelete = "person:delete"
+	// ScopeAPIKeyAdmin manage api keys
+	ScopeAPIKeyAdmin = "apikey:admin"
+	// ScopeLogAdmin inspect and adjust log level at runtime
+	ScopeLogAdmin = "log:admin"
+	// ScopeBackupAdmin trigger database backups
+	ScopeBackupAdmin = "backup:admin"
+	// ScopeMetricsRead scrape metrics of an admin listener reachable beyond localhost
+	ScopeMetricsRead = "metrics:read"
+	// ScopeProfileAdmin collect profiles of an admin listener reachable beyond localhost
+	ScopeProfileAdmin = "profile:admin"
+	// ScopeTenantCross select any tenant with the tenant header, not granted by any role
+	ScopeTenantCross = "tenant:cross"
+
+	// RoleReader role granting read only access to persons
+	RoleReader = "reader"
+	// RoleWriter role granting read and write access to persons
+	RoleWriter = "writer"
+	// RoleOperator role granting the service wide admin listener scopes, tenant admins
+	// are not operators
+	RoleOperator = "operator"
+)
+
+// Policy authorization policy mapping roles onto the scopes they grant
+type Policy struct {
+	roles map[string][]string
+}
+
+// NewPolicy create new policy instance with the default role definitions
+func NewPolicy() *Policy {
+	return &Policy{
+		roles: map[string][]string{
+			RoleReader:       {ScopePersonRead},
+			RoleWriter:       {ScopePersonRead, ScopePersonWrite},
+			domain.RoleAdmin: {ScopePersonRead, ScopePersonWrite, ScopePersonDelete, ScopeAPIKeyAdmin},
+			RoleOperator:     {ScopeLogAdmin, ScopeBackupAdmin, ScopeMetricsRead, ScopeProfileAdmin},
+		},
+	}
+}
+
+// Allows check if principal was granted scope directly or through one of its roles
+func (p *Policy) Allows(principal *domain.Principal, scope string) bool {
+
+	if principal == nil {
+		return false
+	}
+
+	for _, s := range principal.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	for _, role := range principal.Roles {
+		for _, s := range p.roles[role] {
+			if s == scope {
+				return true
+			}
+		}
+	}
+
+	return false
+}
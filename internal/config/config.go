@@ -0,0 +1,282 @@
+// Package config service configuration
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+	"gopkg.in/yaml.v3"
+
+	"github.com/trevatk/go-template/internal/auth"
+	"github.com/trevatk/go-template/internal/db"
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/features"
+	"github.com/trevatk/go-template/internal/logging"
+	"github.com/trevatk/go-template/internal/port"
+	"github.com/trevatk/go-template/internal/tracing"
+)
+
+// FileEnv environment variable naming the configuration file, overridden by --config
+const FileEnv = "CONFIG_FILE"
+
+// masked replacement of secret values when printing
+const masked = "******"
+
+// App application lifecycle configuration
+type App struct {
+	// StartTimeout time given to all start hooks
+	StartTimeout time.Duration `yaml:"start_timeout" env:"APP_START_TIMEOUT"`
+	// StopTimeout time given to all stop hooks, must exceed the http drain timeout
+	StopTimeout time.Duration `yaml:"stop_timeout" env:"APP_STOP_TIMEOUT"`
+}
+
+// Validate check configuration values
+func (c *App) Validate() error {
+
+	if c.StartTimeout <= 0 || c.StopTimeout <= 0 {
+		return errors.New("timeouts must be positive")
+	}
+
+	return nil
+}
+
+// Config typed service configuration
+type Config struct {
+	App         App                      `yaml:"app"`
+	HTTP        port.ServerConfig        `yaml:"http"`
+	Admin       port.AdminConfig         `yaml:"admin"`
+	GRPC        port.GRPCConfig          `yaml:"grpc"`
+	GraphQL     port.GraphQLConfig       `yaml:"graphql"`
+	OpenAPI     port.OpenAPIConfig       `yaml:"openapi"`
+	CORS        port.CORSConfig          `yaml:"cors"`
+	RateLimit   port.RateLimitConfig     `yaml:"rate_limit"`
+	SQLite      db.Config                `yaml:"sqlite"`
+	Log         logging.Config           `yaml:"log"`
+	JWT         auth.JWTConfig           `yaml:"jwt"`
+	Idempotency domain.IdempotencyConfig `yaml:"idempotency"`
+	Tracing     tracing.Config           `yaml:"tracing"`
+	Features    features.Config          `yaml:"features" env:"FEATURE_FLAGS"`
+
+	// args command line arguments the configuration was loaded from, used on reload
+	args []string
+	// file configuration file, empty if none
+	file string
+}
+
+// Default configuration defaults of every section
+func Default() *Config {
+	return &Config{
+		App: App{
+			StartTimeout: time.Second * 15,
+			StopTimeout:  time.Second * 15,
+		},
+		HTTP:        *port.DefaultServerConfig(),
+		Admin:       *port.DefaultAdminConfig(),
+		GRPC:        *port.DefaultGRPCConfig(),
+		GraphQL:     *port.DefaultGraphQLConfig(),
+		OpenAPI:     *port.DefaultOpenAPIConfig(),
+		CORS:        *port.DefaultCORSConfig(),
+		RateLimit:   *port.DefaultRateLimitConfig(),
+		SQLite:      *db.DefaultConfig(),
+		Log:         *logging.DefaultConfig(),
+		JWT:         *auth.DefaultJWTConfig(),
+		Idempotency: *domain.DefaultIdempotencyConfig(),
+		Tracing:     *tracing.DefaultConfig(),
+		Features:    features.Config{},
+	}
+}
+
+// Load configuration with increasing precedence from defaults, yaml file named by
+// --config or $CONFIG_FILE, environment variables and command line flags. Every
+// leaf setting has a flag named after its yaml path, e.g. --http.port=8080.
+// All problems are reported together.
+func Load(args []string) (*Config, error) {
+
+	cfg := Default()
+
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	file := fs.String("config", os.Getenv(FileEnv), "yaml configuration file")
+
+	all := fields(cfg)
+
+	flags := map[string]string{}
+	order := []string{}
+
+	for _, f := range all {
+		path := f.path
+		fs.Func(path, "env $"+f.env, func(v string) error {
+			if _, ok := flags[path]; !ok {
+				order = append(order, path)
+			}
+			flags[path] = v
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("invalid flags %v", err)
+	}
+
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	var errs []error
+
+	if *file != "" {
+		if err := loadFile(cfg, *file); err != nil {
+			return nil, err
+		}
+	}
+
+	// environment
+	for _, f := range all {
+		if f.env == "" {
+			continue
+		}
+		if v, ok := os.LookupEnv(f.env); ok && v != "" {
+			if err := f.set(v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid $%s %v", f.env, err))
+			}
+		}
+	}
+
+	// flags
+	byPath := map[string]field{}
+	for _, f := range all {
+		byPath[f.path] = f
+	}
+
+	for _, path := range order {
+		if err := byPath[path].set(flags[path]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid --%s %v", path, err))
+		}
+	}
+
+	errs = append(errs, cfg.Validate())
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	cfg.args = args
+	cfg.file = *file
+
+	return cfg, nil
+}
+
+// Validate check every section, reporting all problems at once prefixed with their section
+func (c *Config) Validate() error {
+
+	sections := []struct {
+		name string
+		v    interface{ Validate() error }
+	}{
+		{"app", &c.App},
+		{"http", &c.HTTP},
+		{"admin", &c.Admin},
+		{"grpc", &c.GRPC},
+		{"graphql", &c.GraphQL},
+		{"openapi", &c.OpenAPI},
+		{"cors", &c.CORS},
+		{"rate_limit", &c.RateLimit},
+		{"sqlite", &c.SQLite},
+		{"log", &c.Log},
+		{"jwt", &c.JWT},
+		{"idempotency", &c.Idempotency},
+		{"tracing", &c.Tracing},
+	}
+
+	var errs []error
+	for _, s := range sections {
+		err := s.v.Validate()
+		if err == nil {
+			continue
+		}
+
+		// prefix every joined error of the section
+		for _, line := range strings.Split(err.Error(), "\n") {
+			errs = append(errs, fmt.Errorf("%s: %s", s.name, line))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Provide supply configuration and each of its sections to the fx graph
+func (c *Config) Provide() fx.Option {
+	return fx.Supply(
+		c,
+		&c.HTTP,
+		&c.Admin,
+		&c.GRPC,
+		&c.GraphQL,
+		&c.OpenAPI,
+		&c.CORS,
+		&c.RateLimit,
+		&c.SQLite,
+		&c.Log,
+		&c.JWT,
+		&c.Idempotency,
+		&c.Tracing,
+		c.Features,
+	)
+}
+
+// Print write effective configuration as yaml with secrets masked
+func (c *Config) Print(w io.Writer) error {
+
+	copied := *c
+	copied.Log.Outputs = append([]string{}, c.Log.Outputs...)
+	copied.Log.RedactFields = append([]string{}, c.Log.RedactFields...)
+	copied.CORS.AllowedOrigins = append([]string{}, c.CORS.AllowedOrigins...)
+	copied.HTTP.TLS.CipherSuites = append([]string{}, c.HTTP.TLS.CipherSuites...)
+
+	for _, f := range fields(&copied) {
+		if f.secret && !f.value.IsZero() {
+			f.value.SetString(masked)
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(&copied); err != nil {
+		return fmt.Errorf("failed to encode configuration %v", err)
+	}
+
+	return enc.Close()
+}
+
+func loadFile(cfg *Config, path string) error {
+
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+	default:
+		return fmt.Errorf("unsupported configuration file %s, expected yaml", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open configuration file %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to decode configuration file %s %v", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/trevatk/go-template/internal/config"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	return path
+}
+
+// required settings without defaults
+func required(t *testing.T) {
+	t.Setenv("SQLITE_DSN", "file:service.db")
+	t.Setenv("SQLITE_MIGRATIONS_DIR", "migrations")
+	t.Setenv("JWT_JWKS_FILE", "jwks.json")
+}
+
+func TestLoadPrecedence(t *testing.T) {
+
+	assert := assert.New(t)
+
+	required(t)
+
+	path := writeFile(t, "config.yaml", `
+http:
+  port: 9000
+  drain_timeout: 20s
+log:
+  level: debug
+  format: console
+rate_limit:
+  read: 10/s
+`)
+
+	// env overrides file
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_OUTPUT", "stdout, /var/log/service.log")
+
+	// flags override env
+	t.Setenv("HTTP_SERVER_PORT", "9100")
+
+	cfg, err := config.Load([]string{"--config", path, "--http.port=9200", "--log.caller=false"})
+	assert.NoError(err)
+
+	// defaults
+	assert.Equal(time.Second*15, cfg.HTTP.ReadTimeout)
+	assert.Equal("60/1m", cfg.RateLimit.Write)
+
+	// file
+	assert.Equal(time.Second*20, cfg.HTTP.DrainTimeout)
+	assert.Equal("console", cfg.Log.Format)
+	assert.Equal("10/s", cfg.RateLimit.Read)
+
+	// env
+	assert.Equal("warn", cfg.Log.Level)
+	assert.Equal([]string{"stdout", "/var/log/service.log"}, cfg.Log.Outputs)
+	assert.Equal("file:service.db", cfg.SQLite.DSN)
+
+	// flags
+	assert.Equal(9200, cfg.HTTP.Port)
+	assert.False(cfg.Log.Caller)
+
+	// file named by environment
+	t.Setenv(config.FileEnv, path)
+
+	cfg, err = config.Load(nil)
+	assert.NoError(err)
+	assert.Equal(9100, cfg.HTTP.Port)
+}
+
+func TestLoadErrors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	// every problem is reported at once
+	t.Setenv("LOG_LEVEL", "verbose")
+	t.Setenv("HTTP_DRAIN_TIMEOUT", "soon")
+
+	_, err := config.Load([]string{"--rate_limit.write=often"})
+	assert.Error(err)
+
+	for _, expected := range []string{
+		"$HTTP_DRAIN_TIMEOUT",
+		"rate_limit: invalid write rate limit",
+		"log: invalid log level",
+		"sqlite: dsn is required",
+		"jwt: jwks file is required",
+	} {
+		assert.ErrorContains(err, expected)
+	}
+
+	// unknown keys are rejected
+	path := writeFile(t, "config.yaml", "http:\n  prot: 9000\n")
+
+	_, err = config.Load([]string{"--config", path})
+	assert.ErrorContains(err, "prot")
+
+	_, err = config.Load([]string{"--unknown=1"})
+	assert.ErrorContains(err, "unknown")
+
+	_, err = config.Load([]string{"--config", writeFile(t, "config.toml", "")})
+	assert.ErrorContains(err, "unsupported configuration file")
+}
+
+func TestPrint(t *testing.T) {
+
+	assert := assert.New(t)
+
+	required(t)
+	t.Setenv("SQLITE_DSN", "file:service.db?_auth_pass=hunter2")
+
+	cfg, err := config.Load(nil)
+	assert.NoError(err)
+
+	var buf bytes.Buffer
+	assert.NoError(cfg.Print(&buf))
+
+	out := buf.String()
+	assert.NotContains(out, "hunter2")
+	assert.Contains(out, "dsn: '******'")
+	assert.Contains(out, "migrations_dir: migrations")
+	assert.Contains(out, "drain_timeout: 10s")
+
+	// printing does not modify the effective configuration
+	assert.Equal("file:service.db?_auth_pass=hunter2", cfg.SQLite.DSN)
+}
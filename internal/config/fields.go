@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field leaf setting of the configuration struct
+type field struct {
+	// path dotted yaml path, used as flag name
+	path   string
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+// fields list every leaf setting of cfg in declaration order
+func fields(cfg *Config) []field {
+	var out []field
+	walk(reflect.ValueOf(cfg).Elem(), "", &out)
+	return out
+}
+
+func walk(v reflect.Value, prefix string, out *[]field) {
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+
+		if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			walk(fv, path, out)
+			continue
+		}
+
+		*out = append(*out, field{
+			path:   path,
+			env:    sf.Tag.Get("env"),
+			secret: sf.Tag.Get("secret") == "true",
+			value:  fv,
+		})
+	}
+}
+
+// set parse s into the field according to its type, lists are comma separated
+func (f field) set(s string) error {
+
+	v := f.value
+
+	if v.Type() == durationType {
+
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.Bool {
+			return fmt.Errorf("unsupported map type %s", v.Type())
+		}
+
+		// name=bool pairs merged into existing entries
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+
+		for _, pair := range strings.Split(s, ",") {
+
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("expected name=bool, got %q", pair)
+			}
+
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+
+			v.SetMapIndex(reflect.ValueOf(strings.TrimSpace(name)).Convert(v.Type().Key()), reflect.ValueOf(b))
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", v.Type())
+		}
+
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/trevatk/go-template/internal/lifecycle"
+)
+
+// SubscriberGroup fx value group reload subscribers are provided into
+const SubscriberGroup = `group:"config_subscribers"`
+
+// debounce editors emit several events per save, reload once they settled
+const debounce = time.Millisecond * 100
+
+// reloadable settings applied by subscribers at runtime, all others require a restart
+var reloadable = []string{"log.level", "rate_limit", "cors", "features"}
+
+// Subscriber receives every validated configuration change. Subscribers only apply
+// settings which are safe to change at runtime, everything else requires a restart.
+type Subscriber interface {
+	Apply(cfg *Config) error
+}
+
+// SubscriberFunc adapter to use ordinary functions as subscribers
+type SubscriberFunc func(cfg *Config) error
+
+// Apply call f(cfg)
+func (f SubscriberFunc) Apply(cfg *Config) error {
+	return f(cfg)
+}
+
+// AsSubscriber annotate constructor so its result is provided into the reload subscribers group
+func AsSubscriber(constructor interface{}) interface{} {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(Subscriber)),
+		fx.ResultTags(SubscriberGroup),
+	)
+}
+
+// ReloaderParams reloader dependencies
+type ReloaderParams struct {
+	fx.In
+
+	Lifecycle   fx.Lifecycle
+	Config      *Config
+	Logger      *zap.Logger
+	Workers     *lifecycle.Workers
+	Subscribers []Subscriber `group:"config_subscribers"`
+}
+
+// Reloader reloads configuration when its file changes or on SIGHUP
+type Reloader struct {
+	mu          sync.Mutex
+	current     *Config
+	subscribers []Subscriber
+	log         *zap.SugaredLogger
+}
+
+// NewReloader create new reloader instance, watching starts with the application
+func NewReloader(params ReloaderParams) *Reloader {
+
+	reloader := &Reloader{
+		current:     params.Config,
+		subscribers: params.Subscribers,
+		log:         params.Logger.Named("config").Sugar(),
+	}
+
+	params.Lifecycle.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			return reloader.watch(params.Workers)
+		},
+	})
+
+	return reloader
+}
+
+// Current most recently applied configuration
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload load configuration from the original sources again. Invalid configuration,
+// including configuration a subscriber fails to apply, is rejected and the previous
+// configuration stays in effect.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := Load(r.current.args)
+	if err != nil {
+		return fmt.Errorf("rejected configuration reload %v", err)
+	}
+
+	if changed := keepRestartOnly(r.current, next); len(changed) > 0 {
+		r.log.Warnf("changes of %s require a restart, running values are kept", strings.Join(changed, ", "))
+	}
+
+	for i, s := range r.subscribers {
+
+		if err := s.Apply(next); err != nil {
+
+			// roll back subscribers which already applied the new configuration
+			errs := []error{err}
+			for _, applied := range r.subscribers[:i] {
+				if err := applied.Apply(r.current); err != nil {
+					errs = append(errs, fmt.Errorf("failed to restore previous configuration %v", err))
+				}
+			}
+
+			return fmt.Errorf("rejected configuration reload %v", errors.Join(errs...))
+		}
+	}
+
+	r.current = next
+
+	return nil
+}
+
+// watch reload on SIGHUP and on writes to the configuration file until shutdown
+func (r *Reloader) watch(workers *lifecycle.Workers) error {
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var (
+		watcher *fsnotify.Watcher
+		events  chan fsnotify.Event
+		errs    chan error
+	)
+
+	// file is the same for every reload, all of them use the original arguments
+	file := r.Current().file
+
+	if file != "" {
+
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			signal.Stop(hup)
+			return fmt.Errorf("failed to create configuration watcher %v", err)
+		}
+
+		// watch the directory, editors and config maps replace the file rather than writing it
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			signal.Stop(hup)
+			_ = watcher.Close()
+			return fmt.Errorf("failed to watch configuration file %v", err)
+		}
+
+		events = watcher.Events
+		errs = watcher.Errors
+	}
+
+	// config maps swap a ..data symlink without touching the file path itself,
+	// every event in the directory is checked against the resolved content
+	seen := digest(file)
+
+	workers.Go(func(ctx context.Context) {
+
+		defer signal.Stop(hup)
+		if watcher != nil {
+			defer func() { _ = watcher.Close() }()
+		}
+
+		var timer <-chan time.Time
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				r.log.Info("reload configuration on SIGHUP")
+				r.reload()
+			case <-events:
+				timer = time.After(debounce)
+			case err := <-errs:
+				r.log.Errorf("configuration watcher failed %v", err)
+			case <-timer:
+				current := digest(file)
+				if current != nil && bytes.Equal(current, seen) {
+					continue
+				}
+				seen = current
+
+				r.log.Info("reload configuration on file change")
+				r.reload()
+			}
+		}
+	})
+
+	return nil
+}
+
+// digest hash of the file content following symlinks, nil if it can not be read
+func digest(file string) []byte {
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	sum := sha256.Sum256(b)
+
+	return sum[:]
+}
+
+// keepRestartOnly copy settings which are not reloadable from current into next, the
+// paths of those which differ are returned
+func keepRestartOnly(current, next *Config) []string {
+
+	var changed []string
+
+	running := fields(current)
+	for i, f := range fields(next) {
+
+		if isReloadable(f.path) || reflect.DeepEqual(f.value.Interface(), running[i].value.Interface()) {
+			continue
+		}
+
+		changed = append(changed, f.path)
+		f.value.Set(running[i].value)
+	}
+
+	return changed
+}
+
+func isReloadable(path string) bool {
+
+	for _, p := range reloadable {
+		if path == p || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Reloader) reload() {
+
+	if err := r.Reload(); err != nil {
+		r.log.Errorf("%v", err)
+		return
+	}
+
+	r.log.Info("configuration reloaded")
+}
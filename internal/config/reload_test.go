@@ -0,0 +1,217 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/fx/fxtest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/trevatk/go-template/internal/config"
+	"github.com/trevatk/go-template/internal/lifecycle"
+)
+
+// recorder subscriber remembering applied configurations
+type recorder struct {
+	mu      sync.Mutex
+	applied []*config.Config
+}
+
+func (r *recorder) Apply(cfg *config.Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.applied = append(r.applied, cfg)
+	return nil
+}
+
+func (r *recorder) last() *config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.applied) == 0 {
+		return nil
+	}
+	return r.applied[len(r.applied)-1]
+}
+
+func TestReload(t *testing.T) {
+
+	assert := assert.New(t)
+
+	required(t)
+
+	path := writeFile(t, "config.yaml", `
+log:
+  level: info
+features:
+  export: false
+`)
+
+	cfg, err := config.Load([]string{"--config", path})
+	assert.NoError(err)
+
+	rec := &recorder{}
+	workers := lifecycle.NewWorkers()
+	lc := fxtest.NewLifecycle(t)
+
+	reloader := config.NewReloader(config.ReloaderParams{
+		Lifecycle:   lc,
+		Config:      cfg,
+		Logger:      zap.NewNop(),
+		Workers:     workers,
+		Subscribers: []config.Subscriber{rec},
+	})
+
+	lc.RequireStart()
+	defer func() {
+		assert.NoError(workers.Wait(context.Background()))
+		lc.RequireStop()
+	}()
+
+	// valid change is published
+	assert.NoError(os.WriteFile(path, []byte("log:\n  level: debug\nfeatures:\n  export: true\n"), 0600))
+	assert.NoError(reloader.Reload())
+
+	assert.Equal("debug", rec.last().Log.Level)
+	assert.True(rec.last().Features["export"])
+	assert.Equal("debug", reloader.Current().Log.Level)
+
+	// invalid change is rejected and the previous configuration kept
+	assert.NoError(os.WriteFile(path, []byte("log:\n  level: loud\n"), 0600))
+	assert.ErrorContains(reloader.Reload(), "log: invalid log level")
+
+	assert.Equal("debug", rec.last().Log.Level)
+	assert.Equal("debug", reloader.Current().Log.Level)
+
+	// configuration a subscriber fails to apply is rejected and rolled back
+	failing := config.SubscriberFunc(func(cfg *config.Config) error {
+		if cfg.Features["export"] {
+			return errors.New("export unavailable")
+		}
+		return nil
+	})
+
+	rolledBack := &recorder{}
+	rejecting := config.NewReloader(config.ReloaderParams{
+		Lifecycle:   fxtest.NewLifecycle(t),
+		Config:      reloader.Current(),
+		Logger:      zap.NewNop(),
+		Workers:     lifecycle.NewWorkers(),
+		Subscribers: []config.Subscriber{rolledBack, failing},
+	})
+
+	assert.NoError(os.WriteFile(path, []byte("log:\n  level: error\nfeatures:\n  export: true\n"), 0600))
+	assert.ErrorContains(rejecting.Reload(), "export unavailable")
+
+	assert.Equal("debug", rejecting.Current().Log.Level)
+	if assert.Len(rolledBack.applied, 2) {
+		assert.Equal("error", rolledBack.applied[0].Log.Level)
+		assert.Equal("debug", rolledBack.last().Log.Level)
+	}
+
+	// file writes trigger a reload
+	assert.NoError(os.WriteFile(path, []byte("log:\n  level: warn\n"), 0600))
+
+	assert.Eventually(func() bool {
+		last := rec.last()
+		return last != nil && last.Log.Level == "warn"
+	}, time.Second*5, time.Millisecond*20)
+
+	assert.Equal("warn", reloader.Current().Log.Level)
+}
+
+func TestReloadRestartOnly(t *testing.T) {
+
+	assert := assert.New(t)
+
+	required(t)
+
+	path := writeFile(t, "config.yaml", "http:\n  port: 8080\nlog:\n  level: info\n")
+
+	cfg, err := config.Load([]string{"--config", path})
+	assert.NoError(err)
+
+	core, logs := observer.New(zap.WarnLevel)
+
+	rec := &recorder{}
+	reloader := config.NewReloader(config.ReloaderParams{
+		Lifecycle:   fxtest.NewLifecycle(t),
+		Config:      cfg,
+		Logger:      zap.New(core),
+		Workers:     lifecycle.NewWorkers(),
+		Subscribers: []config.Subscriber{rec},
+	})
+
+	// reloadable settings are applied, restart only settings keep their running values
+	assert.NoError(os.WriteFile(path, []byte("http:\n  port: 9000\nlog:\n  level: debug\n"), 0600))
+	assert.NoError(reloader.Reload())
+
+	assert.Equal("debug", reloader.Current().Log.Level)
+	assert.Equal(8080, reloader.Current().HTTP.Port)
+	assert.Equal(8080, rec.last().HTTP.Port)
+
+	if assert.Equal(1, logs.Len()) {
+		assert.Contains(logs.All()[0].Message, "http.port require a restart")
+	}
+}
+
+func TestReloadConfigMap(t *testing.T) {
+
+	assert := assert.New(t)
+
+	required(t)
+
+	// kubelet layout, config.yaml -> ..data/config.yaml and ..data -> timestamped directory
+	dir := t.TempDir()
+
+	version := func(name, content string) {
+		assert.NoError(os.Mkdir(filepath.Join(dir, name), 0700))
+		assert.NoError(os.WriteFile(filepath.Join(dir, name, "config.yaml"), []byte(content), 0600))
+		assert.NoError(os.Symlink(name, filepath.Join(dir, "..data_tmp")))
+		assert.NoError(os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	}
+
+	version("..v1", "log:\n  level: info\n")
+
+	path := filepath.Join(dir, "config.yaml")
+	assert.NoError(os.Symlink(filepath.Join("..data", "config.yaml"), path))
+
+	cfg, err := config.Load([]string{"--config", path})
+	assert.NoError(err)
+
+	rec := &recorder{}
+	workers := lifecycle.NewWorkers()
+	lc := fxtest.NewLifecycle(t)
+
+	reloader := config.NewReloader(config.ReloaderParams{
+		Lifecycle:   lc,
+		Config:      cfg,
+		Logger:      zap.NewNop(),
+		Workers:     workers,
+		Subscribers: []config.Subscriber{rec},
+	})
+
+	lc.RequireStart()
+	defer func() {
+		assert.NoError(workers.Wait(context.Background()))
+		lc.RequireStop()
+	}()
+
+	// the swap never touches config.yaml itself
+	version("..v2", "log:\n  level: warn\n")
+
+	assert.Eventually(func() bool {
+		last := rec.last()
+		return last != nil && last.Log.Level == "warn"
+	}, time.Second*5, time.Millisecond*20)
+
+	assert.Equal("warn", reloader.Current().Log.Level)
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/trevatk/go-template/internal/features"
+	"github.com/trevatk/go-template/internal/port"
+	"github.com/trevatk/go-template/internal/ratelimit"
+)
+
+// NewLogLevelSubscriber apply log level changes. The level is only touched when the
+// configured value changed so adjustments made through the admin endpoint survive
+// unrelated reloads.
+func NewLogLevelSubscriber(cfg *Config, level zap.AtomicLevel) Subscriber {
+
+	applied := cfg.Log.Level
+
+	return SubscriberFunc(func(cfg *Config) error {
+
+		if cfg.Log.Level == applied {
+			return nil
+		}
+
+		l, err := zapcore.ParseLevel(cfg.Log.Level)
+		if err != nil {
+			return fmt.Errorf("invalid log level %v", err)
+		}
+
+		level.SetLevel(l)
+		applied = cfg.Log.Level
+
+		return nil
+	})
+}
+
+// NewRateLimitSubscriber apply rate limits of the http route groups
+func NewRateLimitSubscriber(limiter *ratelimit.Limiter) Subscriber {
+	return SubscriberFunc(func(cfg *Config) error {
+
+		limits, err := cfg.RateLimit.Limits()
+		if err != nil {
+			return err
+		}
+
+		limiter.SetLimits(limits)
+
+		return nil
+	})
+}
+
+// NewFeatureSubscriber apply feature flag states
+func NewFeatureSubscriber(flags *features.Flags) Subscriber {
+	return SubscriberFunc(func(cfg *Config) error {
+		flags.Set(cfg.Features)
+		return nil
+	})
+}
+
+// NewCORSSubscriber apply allowed cors origins
+func NewCORSSubscriber(cors *port.CORS) Subscriber {
+	return SubscriberFunc(func(cfg *Config) error {
+		cors.SetAllowedOrigins(cfg.CORS.AllowedOrigins)
+		return nil
+	})
+}
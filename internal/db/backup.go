@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Backup write consistent copy of the database into dir using VACUUM INTO, returns the path of the backup
+func Backup(ctx context.Context, db *sql.DB, dir string) (string, error) {
+
+	if dir == "" {
+		return "", errors.New("backup directory is unset")
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create backup directory %v", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("backup-%s.db", time.Now().UTC().Format("20060102T150405.000000000Z")))
+
+	// VACUUM INTO refuses to overwrite existing files
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return "", fmt.Errorf("failed to backup database %v", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,46 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/trevatk/go-template/internal/db"
+)
+
+func TestBackup(t *testing.T) {
+
+	assert := assert.New(t)
+
+	sqlite, err := db.NewSQLite(&db.Config{DSN: filepath.Join(t.TempDir(), "source.db")})
+	assert.NoError(err)
+	defer func() { _ = sqlite.Close() }()
+
+	_, err = sqlite.Exec("CREATE TABLE item (name TEXT); INSERT INTO item VALUES ('a'), ('b')")
+	assert.NoError(err)
+
+	dir := filepath.Join(t.TempDir(), "backups")
+
+	path, err := db.Backup(context.Background(), sqlite, dir)
+	assert.NoError(err)
+	assert.Equal(dir, filepath.Dir(path))
+
+	backup, err := sql.Open("sqlite", path)
+	assert.NoError(err)
+	defer func() { _ = backup.Close() }()
+
+	var count int
+	assert.NoError(backup.QueryRow("SELECT COUNT(*) FROM item").Scan(&count))
+	assert.Equal(2, count)
+
+	// every backup gets its own file
+	second, err := db.Backup(context.Background(), sqlite, dir)
+	assert.NoError(err)
+	assert.NotEqual(path, second)
+
+	_, err = db.Backup(context.Background(), sqlite, "")
+	assert.Error(err)
+}
@@ -1,10 +1,13 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite"
@@ -14,11 +17,10 @@ import (
 )
 
 // Migrate go-migrate database migration from file location
-func Migrate(db *sql.DB) error {
+func Migrate(db *sql.DB, migrationDir string) error {
 
-	migrationDir := os.Getenv("SQLITE_MIGRATIONS_DIR")
 	if migrationDir == "" {
-		return errors.New("$SQLITE_MIGRATIONS_DIR is unset")
+		return errors.New("migrations directory is unset")
 	}
 
 	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
@@ -40,3 +42,78 @@ func Migrate(db *sql.DB) error {
 
 	return nil
 }
+
+// Version current schema migration version, dirty if the last migration failed part way
+func Version(ctx context.Context, db *sql.DB) (uint, bool, error) {
+
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+
+		return 0, false, fmt.Errorf("failed to read migration version %v", err)
+	}
+
+	return uint(version), dirty, nil
+}
+
+// CheckMigrations verify every migration of migrationDir has been applied cleanly
+func CheckMigrations(ctx context.Context, db *sql.DB, migrationDir string) error {
+
+	latest, err := LatestVersion(migrationDir)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := Version(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("migration %d failed part way", version)
+	}
+
+	if version < latest {
+		return fmt.Errorf("schema at version %d, expected %d", version, latest)
+	}
+
+	return nil
+}
+
+// LatestVersion highest migration version available in migrationDir
+func LatestVersion(migrationDir string) (uint, error) {
+
+	if migrationDir == "" {
+		return 0, errors.New("migrations directory is unset")
+	}
+
+	files, err := filepath.Glob(filepath.Join(migrationDir, "*.up.sql"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list migrations %v", err)
+	}
+
+	var latest uint
+	for _, f := range files {
+
+		prefix, _, _ := strings.Cut(filepath.Base(f), "_")
+
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %s", filepath.Base(f))
+		}
+
+		if uint(v) > latest {
+			latest = uint(v)
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,40 @@
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trevatk/go-template/internal/db"
+)
+
+func TestLatestVersion(t *testing.T) {
+
+	assert := assert.New(t)
+
+	version, err := db.LatestVersion("./../../migrations")
+	assert.NoError(err)
+	assert.Equal(uint(6), version)
+
+	_, err = db.LatestVersion("")
+	assert.Error(err)
+}
+
+func TestCheckMigrations(t *testing.T) {
+
+	assert := assert.New(t)
+
+	sqlite, err := db.NewSQLite(&db.Config{DSN: filepath.Join(t.TempDir(), "migrate.db")})
+	assert.NoError(err)
+	defer func() { _ = sqlite.Close() }()
+
+	assert.NoError(db.Migrate(sqlite, "./../../migrations"))
+	assert.NoError(db.CheckMigrations(context.Background(), sqlite, "./../../migrations"))
+
+	// probe deadlines bound the version query
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorContains(db.CheckMigrations(ctx, sqlite, "./../../migrations"), "failed to read migration version")
+}
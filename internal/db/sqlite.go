@@ -5,18 +5,51 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"os"
 )
 
+// Config database configuration
+type Config struct {
+	// DSN sqlite data source name
+	DSN string `yaml:"dsn" env:"SQLITE_DSN" secret:"true"`
+	// MigrationsDir directory of golang-migrate migration files
+	MigrationsDir string `yaml:"migrations_dir" env:"SQLITE_MIGRATIONS_DIR"`
+	// BackupDir directory backups triggered through the admin listener are written to
+	BackupDir string `yaml:"backup_dir" env:"SQLITE_BACKUP_DIR"`
+}
+
+// DefaultConfig database defaults, dsn and migrations directory must be configured
+func DefaultConfig() *Config {
+	return &Config{BackupDir: "backups"}
+}
+
+// Validate check configuration values
+func (c *Config) Validate() error {
+
+	var errs []error
+
+	if c.DSN == "" {
+		errs = append(errs, errors.New("dsn is required"))
+	}
+
+	if c.MigrationsDir == "" {
+		errs = append(errs, errors.New("migrations directory is required"))
+	}
+
+	if c.BackupDir == "" {
+		errs = append(errs, errors.New("backup directory is required"))
+	}
+
+	return errors.Join(errs...)
+}
+
 // NewSQLite create new sqlite database connection
-func NewSQLite() (*sql.DB, error) {
+func NewSQLite(cfg *Config) (*sql.DB, error) {
 
-	dsn := os.Getenv("SQLITE_DSN")
-	if dsn == "" {
-		return nil, errors.New("$SQLITE_DSN is unset")
+	if cfg.DSN == "" {
+		return nil, errors.New("sqlite dsn is unset")
 	}
 
-	db, err := sql.Open("sqlite", dsn)
+	db, err := sql.Open("sqlite", cfg.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("error opening sqlite database %v", err)
 	}
@@ -1,22 +1,17 @@
 package db_test
 
 import (
-	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/trevatk/go-template/internal/db"
 )
 
-func init() {
-	_ = os.Setenv("SQLITE_DSN", "./testfiles/sqlite/persons.db")
-}
-
 func TestNewSQLite(t *testing.T) {
 
 	assert := assert.New(t)
 
-	db, err := db.NewSQLite()
+	db, err := db.NewSQLite(&db.Config{DSN: "./testfiles/sqlite/persons.db"})
 	assert.NoError(err)
 
 	defer func() { _ = db.Close() }()
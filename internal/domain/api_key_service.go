@@ -0,0 +1,276 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/trevatk/go-template/internal/repository/apikeys"
+)
+
+var (
+	// ErrKeyExpired service level error message when api key is past its expiry
+	ErrKeyExpired = errors.New("api key expired")
+	// ErrKeyRevoked service level error message when api key has been revoked
+	ErrKeyRevoked = errors.New("api key revoked")
+)
+
+// NewAPIKey application layer model
+type NewAPIKey struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// NewAPIKeyRequest request layer model used for validation of requests
+// using chi render bind
+type NewAPIKeyRequest struct {
+	*NewAPIKey
+}
+
+// Bind callback used to validate new api key request model
+func (nkr *NewAPIKeyRequest) Bind(_ *http.Request) error {
+
+	if nkr.NewAPIKey == nil {
+		return errors.New("no api key details provided")
+	}
+
+	if nkr.NewAPIKey.Name == "" {
+		return errors.New("no name provided")
+	}
+
+	for _, scope := range nkr.NewAPIKey.Scopes {
+		if scope == "" || strings.ContainsAny(scope, " \t\n") {
+			return fmt.Errorf("invalid scope %q", scope)
+		}
+	}
+
+	if nkr.NewAPIKey.ExpiresAt != nil && nkr.NewAPIKey.ExpiresAt.Before(time.Now()) {
+		return errors.New("expiry must be in the future")
+	}
+
+	return nil
+}
+
+// APIKey application layer model, never includes key material
+type APIKey struct {
+	ID        int64      `json:"id"`
+	TenantID  string     `json:"tenant_id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKey newly issued api key, plaintext key is only available once
+type CreatedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyService application layer to facilitate calls to business layer for all api key related models
+type APIKeyService struct {
+	db         *sql.DB
+	authorizer ScopeAuthorizer
+}
+
+// NewAPIKeyService create new api key service instance
+func NewAPIKeyService(db *sql.DB, authorizer ScopeAuthorizer) *APIKeyService {
+	return &APIKeyService{
+		db:         db,
+		authorizer: authorizer,
+	}
+}
+
+// Create issue new api key bound to context tenant, keys may only carry scopes granted
+// to the calling principal
+func (ks *APIKeyService) Create(ctx context.Context, newAPIKey *NewAPIKey) (*CreatedAPIKey, error) {
+
+	tenantID, err := tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	principal, _ := PrincipalFromContext(ctx)
+	for _, scope := range newAPIKey.Scopes {
+		if !ks.authorizer.Allows(principal, scope) {
+			return nil, fmt.Errorf("%w: scope %s not granted to caller", ErrForbidden, scope)
+		}
+	}
+
+	prefix, key, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt sql.NullTime
+	if newAPIKey.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: newAPIKey.ExpiresAt.UTC(), Valid: true}
+	}
+
+	conn, err := ks.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	sqlKey, err := apikeys.New(conn).InsertAPIKey(ctx, &apikeys.InsertAPIKeyParams{
+		TenantID:  tenantID,
+		Name:      newAPIKey.Name,
+		Prefix:    prefix,
+		KeyHash:   hashAPIKey(key),
+		Scopes:    strings.Join(newAPIKey.Scopes, " "),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert new api key %v", err)
+	}
+
+	return &CreatedAPIKey{APIKey: transformSQLAPIKey(sqlKey), Key: key}, nil
+}
+
+// List retrieve all api keys of context tenant
+func (ks *APIKeyService) List(ctx context.Context) ([]*APIKey, error) {
+
+	tenantID, err := tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ks.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	sqlKeys, err := apikeys.New(conn).ListAPIKeys(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing list api keys query %v", err)
+	}
+
+	keys := make([]*APIKey, 0, len(sqlKeys))
+	for _, k := range sqlKeys {
+		keys = append(keys, transformSQLAPIKey(k))
+	}
+
+	return keys, nil
+}
+
+// Revoke revoke api key of context tenant
+func (ks *APIKeyService) Revoke(ctx context.Context, id int64) error {
+
+	tenantID, err := tenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn, err := ks.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	result, err := apikeys.New(conn).RevokeAPIKey(ctx, &apikeys.RevokeAPIKeyParams{ID: id, TenantID: tenantID})
+	if err != nil {
+		return fmt.Errorf("error executing revoke api key query %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected %v", err)
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Authenticate resolve plaintext api key to an active api key
+func (ks *APIKeyService) Authenticate(ctx context.Context, key string) (*APIKey, error) {
+
+	conn, err := ks.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	sqlKey, err := apikeys.New(conn).ReadAPIKeyByHash(ctx, hashAPIKey(key))
+	if err != nil {
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("error executing read api key query %v", err)
+	}
+
+	apiKey := transformSQLAPIKey(sqlKey)
+
+	if apiKey.RevokedAt != nil {
+		return nil, ErrKeyRevoked
+	}
+
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(time.Now()) {
+		return nil, ErrKeyExpired
+	}
+
+	return apiKey, nil
+}
+
+// generateAPIKey create random api key with a short public prefix used to
+// identify the key in listings
+func generateAPIKey() (string, string, error) {
+
+	b := make([]byte, 36)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key %v", err)
+	}
+
+	prefix := hex.EncodeToString(b[:4])
+	secret := base64.RawURLEncoding.EncodeToString(b[4:])
+
+	return prefix, prefix + "." + secret, nil
+}
+
+// hashAPIKey keys carry 256 bits of entropy so a fast hash is sufficient
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// transform business model into application model
+func transformSQLAPIKey(sqlKey *apikeys.ApiKey) *APIKey {
+
+	var key APIKey
+
+	key.ID = sqlKey.ID
+	key.TenantID = sqlKey.TenantID
+	key.Name = sqlKey.Name
+	key.Prefix = sqlKey.Prefix
+	key.Scopes = strings.Fields(sqlKey.Scopes)
+	key.CreatedAt = sqlKey.CreatedAt
+
+	if sqlKey.ExpiresAt.Valid {
+		expiresAt := sqlKey.ExpiresAt.Time
+		key.ExpiresAt = &expiresAt
+	}
+
+	if sqlKey.RevokedAt.Valid {
+		revokedAt := sqlKey.RevokedAt.Time
+		key.RevokedAt = &revokedAt
+	}
+
+	return &key
+}
@@ -3,12 +3,16 @@ package domain
 
 // Bundle service bundle
 type Bundle struct {
-	PersonService *PersonService
+	PersonService      *PersonService
+	APIKeyService      *APIKeyService
+	IdempotencyService *IdempotencyService
 }
 
 // NewBundle create new service bundle
-func NewBundle(personService *PersonService) *Bundle {
+func NewBundle(personService *PersonService, apiKeyService *APIKeyService, idempotencyService *IdempotencyService) *Bundle {
 	return &Bundle{
-		PersonService: personService,
+		PersonService:      personService,
+		APIKeyService:      apiKeyService,
+		IdempotencyService: idempotencyService,
 	}
 }
@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/trevatk/go-template/internal/repository/idempotency"
+)
+
+var (
+	// ErrIdempotencyMismatch service level error message when an idempotency key is reused with a different payload
+	ErrIdempotencyMismatch = errors.New("idempotency key reused with different request")
+	// ErrIdempotencyInProgress service level error message when the original request is still being processed
+	ErrIdempotencyInProgress = errors.New("idempotency key request in progress")
+)
+
+// StoredResponse response recorded for an idempotency key
+type StoredResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyScope scope of idempotency keys, keys are unique per tenant, client and request target
+func IdempotencyScope(tenantID, client, target string) string {
+	return idempotencyTenantPrefix(tenantID) + client + "|" + target
+}
+
+// prefix shared by all scopes of a tenant
+func idempotencyTenantPrefix(tenantID string) string {
+	return tenantID + "|"
+}
+
+// IdempotencyService application layer to facilitate calls to business layer for idempotency keys
+type IdempotencyService struct {
+	db    *sql.DB
+	ttl   time.Duration
+	lease time.Duration
+	now   func() time.Time
+}
+
+// IdempotencyConfig idempotency key configuration
+type IdempotencyConfig struct {
+	// TTL duration stored responses are replayed for
+	TTL time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL"`
+	// Lease duration a key stays reserved for a request in progress, abandoned
+	// reservations, e.g. of crashed processes, are taken over once it passed
+	Lease time.Duration `yaml:"lease" env:"IDEMPOTENCY_LEASE"`
+}
+
+// DefaultIdempotencyConfig idempotency defaults
+func DefaultIdempotencyConfig() *IdempotencyConfig {
+	return &IdempotencyConfig{TTL: time.Hour * 24, Lease: time.Minute}
+}
+
+// Validate check configuration values
+func (c *IdempotencyConfig) Validate() error {
+
+	if c.TTL <= 0 {
+		return fmt.Errorf("invalid ttl %s", c.TTL)
+	}
+
+	if c.Lease <= 0 || c.Lease > c.TTL {
+		return fmt.Errorf("invalid lease %s, must be positive and not exceed the ttl", c.Lease)
+	}
+
+	return nil
+}
+
+// NewIdempotencyService create new idempotency service instance, keys expire after the configured ttl
+func NewIdempotencyService(db *sql.DB, cfg *IdempotencyConfig) (*IdempotencyService, error) {
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &IdempotencyService{db: db, ttl: cfg.TTL, lease: cfg.Lease, now: time.Now}, nil
+}
+
+// Reserve claim idempotency key for request identified by hash. A nil response means the
+// caller owns the key and must Complete or Release it, otherwise the stored response
+// of the original request is returned.
+func (is *IdempotencyService) Reserve(ctx context.Context, scope, key, hash string) (*StoredResponse, error) {
+
+	conn, err := is.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	q := idempotency.New(conn)
+	now := is.now()
+
+	_, err = q.DeleteExpiredIdempotencyKeys(ctx, now.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("error executing delete expired idempotency keys query %v", err)
+	}
+
+	_, err = q.ReserveIdempotencyKey(ctx, &idempotency.ReserveIdempotencyKeyParams{
+		Scope:          scope,
+		IdempotencyKey: key,
+		RequestHash:    hash,
+		ExpiresAt:      now.Add(is.lease).Unix(),
+	})
+	if err == nil {
+		return nil, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("error executing reserve idempotency key query %v", err)
+	}
+
+	// key already taken
+	sqlKey, err := q.ReadIdempotencyKey(ctx, &idempotency.ReadIdempotencyKeyParams{
+		Scope:          scope,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+
+		// released between reservation and read, let the client retry
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdempotencyInProgress
+		}
+
+		return nil, fmt.Errorf("error executing read idempotency key query %v", err)
+	}
+
+	if sqlKey.RequestHash != hash {
+		return nil, ErrIdempotencyMismatch
+	}
+
+	if sqlKey.Status == 0 {
+		return nil, ErrIdempotencyInProgress
+	}
+
+	return &StoredResponse{
+		Status:      int(sqlKey.Status),
+		ContentType: sqlKey.ContentType,
+		Body:        sqlKey.Body,
+	}, nil
+}
+
+// Complete store response of reserved idempotency key for replay until the ttl passed
+func (is *IdempotencyService) Complete(ctx context.Context, scope, key string, response *StoredResponse) error {
+
+	conn, err := is.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	err = idempotency.New(conn).CompleteIdempotencyKey(ctx, &idempotency.CompleteIdempotencyKeyParams{
+		Status:         int64(response.Status),
+		ContentType:    response.ContentType,
+		Body:           response.Body,
+		ExpiresAt:      is.now().Add(is.ttl).Unix(),
+		Scope:          scope,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return fmt.Errorf("error executing complete idempotency key query %v", err)
+	}
+
+	return nil
+}
+
+// Release drop reservation so the request can be retried with the same key
+func (is *IdempotencyService) Release(ctx context.Context, scope, key string) error {
+
+	conn, err := is.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	err = idempotency.New(conn).DeleteIdempotencyKey(ctx, &idempotency.DeleteIdempotencyKeyParams{
+		Scope:          scope,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return fmt.Errorf("error executing delete idempotency key query %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"context"
+	"sync"
+)
+
+// watchBuffer events buffered per watcher, slower watchers are disconnected
+const watchBuffer = 64
+
+// PersonEvent committed change of a person record
+type PersonEvent struct {
+	// Action one of the history actions created, updated, transferred, deleted or erased
+	Action   string  `json:"action"`
+	PersonID int64   `json:"person_id"`
+	TenantID string  `json:"tenant_id"`
+	Person   *Person `json:"person,omitempty"`
+}
+
+// watcher receives events of a single tenant
+type watcher struct {
+	tenantID string
+	ch       chan *PersonEvent
+}
+
+// broker fan out of committed person events to watchers
+type broker struct {
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{watchers: map[*watcher]struct{}{}}
+}
+
+// publish deliver event to all watchers of its tenant without blocking, watchers whose
+// buffer is full are dropped and their channel closed
+func (b *broker) publish(event *PersonEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for w := range b.watchers {
+
+		if w.tenantID != event.TenantID {
+			continue
+		}
+
+		select {
+		case w.ch <- event:
+		default:
+			delete(b.watchers, w)
+			close(w.ch)
+		}
+	}
+}
+
+func (b *broker) subscribe(tenantID string) *watcher {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	w := &watcher{tenantID: tenantID, ch: make(chan *PersonEvent, watchBuffer)}
+	b.watchers[w] = struct{}{}
+
+	return w
+}
+
+func (b *broker) unsubscribe(w *watcher) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.watchers[w]; ok {
+		delete(b.watchers, w)
+		close(w.ch)
+	}
+}
+
+// Watch stream committed changes of persons in the context tenant. The channel is closed
+// when ctx is done or the caller falls too far behind.
+func (ps *PersonService) Watch(ctx context.Context) (<-chan *PersonEvent, error) {
+
+	tenantID, err := tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w := ps.events.subscribe(tenantID)
+
+	go func() {
+		<-ctx.Done()
+		ps.events.unsubscribe(w)
+	}()
+
+	return w.ch, nil
+}
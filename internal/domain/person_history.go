@@ -0,0 +1,276 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/trevatk/go-template/internal/repository/idempotency"
+	"github.com/trevatk/go-template/internal/repository/persons"
+	"github.com/trevatk/go-template/internal/tracing"
+)
+
+const (
+	// HistoryActionCreated person record inserted
+	HistoryActionCreated = "created"
+	// HistoryActionUpdated person record modified
+	HistoryActionUpdated = "updated"
+	// HistoryActionDeleted person record removed
+	HistoryActionDeleted = "deleted"
+	// HistoryActionTransferred person record handed over to a new owner
+	HistoryActionTransferred = "transferred"
+	// HistoryActionExported person data exported on data subject access request
+	HistoryActionExported = "exported"
+	// HistoryActionErased person data anonymized on data subject erasure request
+	HistoryActionErased = "erased"
+
+	// erasedValue replacement for personal data removed by erasure
+	erasedValue = "[erased]"
+)
+
+// PersonHistory application layer model of a single audit trail entry
+type PersonHistory struct {
+	ID        int64           `json:"id"`
+	PersonID  int64           `json:"person_id"`
+	TenantID  string          `json:"tenant_id"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	Snapshot  json.RawMessage `json:"snapshot,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// PersonExport machine-readable bundle of all data held about a person
+type PersonExport struct {
+	Person     *Person          `json:"person"`
+	History    []*PersonHistory `json:"history"`
+	ExportedAt time.Time        `json:"exported_at"`
+}
+
+// Export collect person record and audit trail for a data subject access request
+func (ps *PersonService) Export(ctx context.Context, id int64) (*PersonExport, error) {
+
+	ctx, span := startSpan(ctx, "PersonService.Export")
+	defer span.End()
+
+	export := &PersonExport{}
+
+	err := ps.withTx(ctx, func(q *persons.Queries, tenantID string) error {
+
+		sqlPerson, err := q.ReadPerson(ctx, &persons.ReadPersonParams{ID: id, TenantID: tenantID})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error executing read person query %v", err)
+		} else if err == nil {
+			export.Person = transformSQLPerson(sqlPerson)
+		}
+
+		sqlHistory, err := q.ListPersonHistory(ctx, &persons.ListPersonHistoryParams{PersonID: id, TenantID: tenantID})
+		if err != nil {
+			return fmt.Errorf("error executing list person history query %v", err)
+		}
+
+		// person may have been deleted while the audit trail remains
+		if export.Person == nil && len(sqlHistory) == 0 {
+			return ErrNotFound
+		}
+
+		export.History = make([]*PersonHistory, 0, len(sqlHistory))
+		for _, h := range sqlHistory {
+			export.History = append(export.History, transformSQLPersonHistory(h))
+		}
+
+		export.ExportedAt = time.Now().UTC()
+
+		return recordHistory(ctx, q, tenantID, id, HistoryActionExported, nil)
+	})
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	return export, nil
+}
+
+// HistoryMany batch read the audit trails of several persons of the context tenant, keyed
+// by person id in insertion order
+func (ps *PersonService) HistoryMany(ctx context.Context, personIDs []int64) (map[int64][]*PersonHistory, error) {
+
+	ctx, span := startSpan(ctx, "PersonService.HistoryMany")
+	defer span.End()
+
+	tenantID, err := tenant(ctx)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	conn, err := ps.db.Conn(ctx)
+	if err != nil {
+		return nil, spanError(span, fmt.Errorf("failed to get database connection %v", err))
+	}
+	defer func() { _ = conn.Close() }()
+
+	sqlHistory, err := persons.New(tracing.WrapDBTX(conn)).ListPersonsHistory(ctx, &persons.ListPersonsHistoryParams{
+		TenantID:  tenantID,
+		PersonIds: personIDs,
+	})
+	if err != nil {
+		return nil, spanError(span, fmt.Errorf("error executing list persons history query %v", err))
+	}
+
+	result := make(map[int64][]*PersonHistory, len(personIDs))
+	for _, h := range sqlHistory {
+		result[h.PersonID] = append(result[h.PersonID], transformSQLPersonHistory(h))
+	}
+
+	return result, nil
+}
+
+// Erase anonymize person record and audit trail for a data subject erasure request.
+// Record and history rows are kept so references to the person id remain valid,
+// stored idempotent responses carrying the person are dropped.
+func (ps *PersonService) Erase(ctx context.Context, id int64) error {
+
+	ctx, span := startSpan(ctx, "PersonService.Erase")
+	defer span.End()
+
+	var erased *Person
+
+	err := ps.inTx(ctx, func(tx *sql.Tx, tenantID string) error {
+
+		q := persons.New(tracing.WrapDBTX(tx))
+
+		sqlPerson, err := q.ReadPerson(ctx, &persons.ReadPersonParams{ID: id, TenantID: tenantID})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error executing read person query %v", err)
+		}
+		exists := err == nil
+
+		// audit trail of a deleted person may only be erased by admins
+		owner := ""
+		if exists {
+			owner = sqlPerson.Owner
+		}
+
+		sqlHistory, err := q.ListPersonHistory(ctx, &persons.ListPersonHistoryParams{PersonID: id, TenantID: tenantID})
+		if err != nil {
+			return fmt.Errorf("error executing list person history query %v", err)
+		}
+
+		if !exists && len(sqlHistory) == 0 {
+			return ErrNotFound
+		}
+
+		err = checkOwner(ctx, owner)
+		if err != nil {
+			return err
+		}
+
+		anonymous := &Person{ID: id, TenantID: tenantID, FirstName: erasedValue, LastName: erasedValue, Email: erasedValue}
+
+		if exists {
+			sqlPerson, err := q.UpdatePerson(ctx, &persons.UpdatePersonParams{
+				Fname:    erasedValue,
+				Lname:    erasedValue,
+				Email:    erasedValue,
+				ID:       id,
+				TenantID: tenantID,
+			})
+			if err != nil {
+				return fmt.Errorf("error executing update person query %v", err)
+			}
+			anonymous = transformSQLPerson(sqlPerson)
+		}
+
+		snapshot, err := marshalSnapshot(anonymous)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.AnonymizePersonHistory(ctx, &persons.AnonymizePersonHistoryParams{
+			Snapshot: snapshot,
+			PersonID: id,
+			TenantID: tenantID,
+		})
+		if err != nil {
+			return fmt.Errorf("error executing anonymize person history query %v", err)
+		}
+
+		_, err = idempotency.New(tracing.WrapDBTX(tx)).DeletePersonIdempotencyKeys(ctx, &idempotency.DeletePersonIdempotencyKeysParams{
+			TenantPrefix: idempotencyTenantPrefix(tenantID),
+			PersonID:     id,
+		})
+		if err != nil {
+			return fmt.Errorf("error executing delete person idempotency keys query %v", err)
+		}
+
+		erased = anonymous
+
+		return recordHistory(ctx, q, tenantID, id, HistoryActionErased, nil)
+	})
+	if err != nil {
+		return spanError(span, err)
+	}
+
+	ps.publish(HistoryActionErased, id, erased)
+
+	return nil
+}
+
+// recordHistory append action to person audit trail, snapshot is optional
+func recordHistory(ctx context.Context, q *persons.Queries, tenantID string, personID int64, action string, snapshot *Person) error {
+
+	var (
+		data sql.NullString
+		err  error
+	)
+
+	if snapshot != nil {
+		data, err = marshalSnapshot(snapshot)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = q.InsertPersonHistory(ctx, &persons.InsertPersonHistoryParams{
+		PersonID: personID,
+		TenantID: tenantID,
+		Action:   action,
+		Snapshot: data,
+		Actor:    actor(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to insert person history %v", err)
+	}
+
+	return nil
+}
+
+func marshalSnapshot(person *Person) (sql.NullString, error) {
+
+	b, err := json.Marshal(person)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to marshal person snapshot %v", err)
+	}
+
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+// transform business model into application model
+func transformSQLPersonHistory(sqlHistory *persons.PersonHistory) *PersonHistory {
+
+	var history PersonHistory
+
+	history.ID = sqlHistory.ID
+	history.PersonID = sqlHistory.PersonID
+	history.TenantID = sqlHistory.TenantID
+	history.Action = sqlHistory.Action
+	history.Actor = sqlHistory.Actor
+	history.CreatedAt = sqlHistory.CreatedAt
+
+	if sqlHistory.Snapshot.Valid {
+		history.Snapshot = json.RawMessage(sqlHistory.Snapshot.String)
+	}
+
+	return &history
+}
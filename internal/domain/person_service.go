@@ -6,14 +6,25 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/trevatk/go-template/internal/repository/persons"
+	"github.com/trevatk/go-template/internal/tracing"
+)
+
+const (
+	// DefaultPageSize persons per page when no limit is requested
+	DefaultPageSize = 50
+	// MaxPageSize upper bound of persons per page
+	MaxPageSize = 500
 )
 
 var (
 	// ErrNotFound service level error message when resource is not found
 	ErrNotFound = errors.New("resource id not found")
+	// ErrForbidden service level error message when caller may not modify resource
+	ErrForbidden = errors.New("operation not permitted")
 )
 
 // NewPerson application layer model
@@ -50,6 +61,8 @@ func (npr *NewPersonRequest) Bind(_ *http.Request) error {
 // Person application layer model
 type Person struct {
 	ID        int64     `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Owner     string    `json:"owner"`
 	FirstName string    `json:"first_name"`
 	LastName  string    `json:"last_name"`
 	Email     string    `json:"email"`
@@ -57,6 +70,13 @@ type Person struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// PersonPage page of persons ordered by id
+type PersonPage struct {
+	Persons []*Person `json:"persons"`
+	// NextAfter cursor of the following page, zero on the last page
+	NextAfter int64 `json:"next_after"`
+}
+
 // UpdatePerson application layer model
 type UpdatePerson struct {
 	ID        int64  `json:"id"`
@@ -81,109 +101,464 @@ func (upr *UpdatePersonRequest) Bind(_ *http.Request) error {
 	return nil
 }
 
+// TransferPerson application layer model
+type TransferPerson struct {
+	Owner string `json:"owner"`
+}
+
+// TransferPersonRequest request layer model used for validation of requests
+// using chi render bind
+type TransferPersonRequest struct {
+	*TransferPerson
+}
+
+// Bind callback used to validate transfer person request model
+func (tpr *TransferPersonRequest) Bind(_ *http.Request) error {
+
+	if tpr.TransferPerson == nil {
+		return errors.New("invalid request object")
+	}
+
+	if tpr.TransferPerson.Owner == "" {
+		return errors.New("no owner provided")
+	}
+
+	return nil
+}
+
 // PersonService application layer to facilitate calls to business layer for all person related models
 type PersonService struct {
-	db *sql.DB
+	db     *sql.DB
+	events *broker
 }
 
 // NewPersonService create new person service instance
 func NewPersonService(db *sql.DB) *PersonService {
 	return &PersonService{
-		db: db,
+		db:     db,
+		events: newBroker(),
 	}
 }
 
 // Create insert new person into database
 func (ps *PersonService) Create(ctx context.Context, newPerson *NewPerson) (*Person, error) {
 
-	conn, err := ps.db.Conn(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get database connection %v", err)
-	}
-	defer func() { _ = conn.Close() }()
+	ctx, span := startSpan(ctx, "PersonService.Create")
+	defer span.End()
+
+	var person *Person
 
-	sqlPerson, err := persons.New(conn).InsertPerson(ctx, &persons.InsertPersonParams{
-		Fname: newPerson.FirstName,
-		Lname: newPerson.LastName,
-		Email: newPerson.Email,
+	err := ps.withTx(ctx, func(q *persons.Queries, tenantID string) error {
+
+		sqlPerson, err := q.InsertPerson(ctx, &persons.InsertPersonParams{
+			Fname:    newPerson.FirstName,
+			Lname:    newPerson.LastName,
+			Email:    newPerson.Email,
+			TenantID: tenantID,
+			Owner:    actor(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert new person %v", err)
+		}
+
+		person = transformSQLPerson(sqlPerson)
+
+		return recordHistory(ctx, q, tenantID, person.ID, HistoryActionCreated, person)
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert new person %v", err)
+		return nil, spanError(span, err)
 	}
 
-	return transformSQLPerson(sqlPerson), nil
+	ps.publish(HistoryActionCreated, person.ID, person)
+
+	return person, nil
 }
 
 // ReadPerson retrieve person by id
 func (ps *PersonService) Read(ctx context.Context, id int64) (*Person, error) {
 
+	ctx, span := startSpan(ctx, "PersonService.Read")
+	defer span.End()
+
+	tenantID, err := tenant(ctx)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
 	conn, err := ps.db.Conn(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get database connection %v", err)
+		return nil, spanError(span, fmt.Errorf("failed to get database connection %v", err))
 	}
 	defer func() { _ = conn.Close() }()
 
-	sqlPerson, err := persons.New(conn).ReadPerson(ctx, id)
+	sqlPerson, err := persons.New(tracing.WrapDBTX(conn)).ReadPerson(ctx, &persons.ReadPersonParams{ID: id, TenantID: tenantID})
 	if err != nil {
 
 		if err == sql.ErrNoRows {
-			return nil, ErrNotFound
+			return nil, spanError(span, ErrNotFound)
 		}
 
-		return nil, fmt.Errorf("error executing read person query %v", err)
+		return nil, spanError(span, fmt.Errorf("error executing read person query %v", err))
 	}
 
 	return transformSQLPerson(sqlPerson), nil
 }
 
-// Update update existing person record
-func (ps *PersonService) Update(ctx context.Context, updatePerson *UpdatePerson) (*Person, error) {
+// ReadMany batch read persons of the context tenant by id, ids that do not exist are
+// missing from the result
+func (ps *PersonService) ReadMany(ctx context.Context, ids []int64) (map[int64]*Person, error) {
+
+	ctx, span := startSpan(ctx, "PersonService.ReadMany")
+	defer span.End()
+
+	tenantID, err := tenant(ctx)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	conn, err := ps.db.Conn(ctx)
+	if err != nil {
+		return nil, spanError(span, fmt.Errorf("failed to get database connection %v", err))
+	}
+	defer func() { _ = conn.Close() }()
+
+	sqlPersons, err := persons.New(tracing.WrapDBTX(conn)).ReadPersons(ctx, &persons.ReadPersonsParams{TenantID: tenantID, Ids: ids})
+	if err != nil {
+		return nil, spanError(span, fmt.Errorf("error executing read persons query %v", err))
+	}
+
+	result := make(map[int64]*Person, len(sqlPersons))
+	for _, sqlPerson := range sqlPersons {
+		result[sqlPerson.ID] = transformSQLPerson(sqlPerson)
+	}
+
+	return result, nil
+}
+
+// List page through persons of the context tenant ordered by id, starting after the given
+// id. Limit is clamped to MaxPageSize, zero selects DefaultPageSize.
+func (ps *PersonService) List(ctx context.Context, after int64, limit int) (*PersonPage, error) {
+
+	ctx, span := startSpan(ctx, "PersonService.List")
+	defer span.End()
+
+	tenantID, err := tenant(ctx)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	limit = pageSize(limit)
 
 	conn, err := ps.db.Conn(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get database connection %v", err)
+		return nil, spanError(span, fmt.Errorf("failed to get database connection %v", err))
 	}
 	defer func() { _ = conn.Close() }()
 
-	sqlPerson, err := persons.New(conn).UpdatePerson(ctx, &persons.UpdatePersonParams{
-		Fname: updatePerson.FirstName,
-		Lname: updatePerson.LastName,
-		Email: updatePerson.Email,
-		ID:    updatePerson.ID,
+	// one extra row tells whether another page follows
+	sqlPersons, err := persons.New(tracing.WrapDBTX(conn)).ListPersons(ctx, &persons.ListPersonsParams{
+		TenantID: tenantID,
+		ID:       after,
+		Limit:    int64(limit) + 1,
 	})
 	if err != nil {
+		return nil, spanError(span, fmt.Errorf("error executing list persons query %v", err))
+	}
 
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
+	return newPersonPage(sqlPersons, limit), nil
+}
+
+// Search page through persons of the context tenant whose first name, last name or email
+// contain query, case insensitive for ascii. Paging follows List.
+func (ps *PersonService) Search(ctx context.Context, query string, after int64, limit int) (*PersonPage, error) {
+
+	ctx, span := startSpan(ctx, "PersonService.Search")
+	defer span.End()
+
+	tenantID, err := tenant(ctx)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	limit = pageSize(limit)
+
+	conn, err := ps.db.Conn(ctx)
+	if err != nil {
+		return nil, spanError(span, fmt.Errorf("failed to get database connection %v", err))
+	}
+	defer func() { _ = conn.Close() }()
+
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+
+	sqlPersons, err := persons.New(tracing.WrapDBTX(conn)).SearchPersons(ctx, &persons.SearchPersonsParams{
+		TenantID: tenantID,
+		ID:       after,
+		Fname:    pattern,
+		Lname:    pattern,
+		Email:    pattern,
+		Limit:    int64(limit) + 1,
+	})
+	if err != nil {
+		return nil, spanError(span, fmt.Errorf("error executing search persons query %v", err))
+	}
+
+	return newPersonPage(sqlPersons, limit), nil
+}
+
+// likeEscaper escape wildcards of like patterns, queries use backslash as escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// pageSize clamp requested limit to MaxPageSize, zero selects DefaultPageSize
+func pageSize(limit int) int {
+
+	if limit <= 0 {
+		return DefaultPageSize
+	} else if limit > MaxPageSize {
+		return MaxPageSize
+	}
+
+	return limit
+}
+
+// newPersonPage build page of at most limit persons, a row beyond limit marks a following page
+func newPersonPage(sqlPersons []*persons.Person, limit int) *PersonPage {
+
+	page := &PersonPage{Persons: make([]*Person, 0, len(sqlPersons))}
+
+	for i, sqlPerson := range sqlPersons {
+
+		if i == limit {
+			page.NextAfter = page.Persons[limit-1].ID
+			break
 		}
 
-		return nil, fmt.Errorf("error executing update person query %v", err)
+		page.Persons = append(page.Persons, transformSQLPerson(sqlPerson))
 	}
 
-	return transformSQLPerson(sqlPerson), nil
+	return page
+}
+
+// Update update existing person record
+func (ps *PersonService) Update(ctx context.Context, updatePerson *UpdatePerson) (*Person, error) {
+
+	ctx, span := startSpan(ctx, "PersonService.Update")
+	defer span.End()
+
+	var person *Person
+
+	err := ps.withTx(ctx, func(q *persons.Queries, tenantID string) error {
+
+		err := ps.loadAndAuthorizeOwner(ctx, q, tenantID, updatePerson.ID)
+		if err != nil {
+			return err
+		}
+
+		sqlPerson, err := q.UpdatePerson(ctx, &persons.UpdatePersonParams{
+			Fname:    updatePerson.FirstName,
+			Lname:    updatePerson.LastName,
+			Email:    updatePerson.Email,
+			ID:       updatePerson.ID,
+			TenantID: tenantID,
+		})
+		if err != nil {
+
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+
+			return fmt.Errorf("error executing update person query %v", err)
+		}
+
+		person = transformSQLPerson(sqlPerson)
+
+		return recordHistory(ctx, q, tenantID, person.ID, HistoryActionUpdated, person)
+	})
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	ps.publish(HistoryActionUpdated, person.ID, person)
+
+	return person, nil
 }
 
 // Delete hard delete person record
 func (ps *PersonService) Delete(ctx context.Context, id int64) error {
 
+	ctx, span := startSpan(ctx, "PersonService.Delete")
+	defer span.End()
+
+	var person *Person
+
+	err := ps.withTx(ctx, func(q *persons.Queries, tenantID string) error {
+
+		sqlPerson, err := q.ReadPerson(ctx, &persons.ReadPersonParams{ID: id, TenantID: tenantID})
+		if err != nil {
+
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+
+			return fmt.Errorf("error executing read person query %v", err)
+		}
+
+		err = checkOwner(ctx, sqlPerson.Owner)
+		if err != nil {
+			return err
+		}
+
+		result, err := q.DeletePerson(ctx, &persons.DeletePersonParams{ID: id, TenantID: tenantID})
+		if err != nil {
+			return fmt.Errorf("error excuting delete person query %v", err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected %v", err)
+		}
+
+		if affected == 0 {
+			return ErrNotFound
+		}
+
+		person = transformSQLPerson(sqlPerson)
+
+		return recordHistory(ctx, q, tenantID, id, HistoryActionDeleted, person)
+	})
+	if err != nil {
+		return spanError(span, err)
+	}
+
+	ps.publish(HistoryActionDeleted, id, person)
+
+	return nil
+}
+
+// TransferOwnership hand person record over to a new owner
+func (ps *PersonService) TransferOwnership(ctx context.Context, id int64, transferPerson *TransferPerson) (*Person, error) {
+
+	ctx, span := startSpan(ctx, "PersonService.TransferOwnership")
+	defer span.End()
+
+	var person *Person
+
+	err := ps.withTx(ctx, func(q *persons.Queries, tenantID string) error {
+
+		err := ps.loadAndAuthorizeOwner(ctx, q, tenantID, id)
+		if err != nil {
+			return err
+		}
+
+		sqlPerson, err := q.TransferPerson(ctx, &persons.TransferPersonParams{
+			Owner:    transferPerson.Owner,
+			ID:       id,
+			TenantID: tenantID,
+		})
+		if err != nil {
+			return fmt.Errorf("error executing transfer person query %v", err)
+		}
+
+		person = transformSQLPerson(sqlPerson)
+
+		return recordHistory(ctx, q, tenantID, person.ID, HistoryActionTransferred, person)
+	})
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	ps.publish(HistoryActionTransferred, person.ID, person)
+
+	return person, nil
+}
+
+// publish notify watchers of a committed change, person is the last known state of the record
+func (ps *PersonService) publish(action string, id int64, person *Person) {
+
+	tenantID := ""
+	if person != nil {
+		tenantID = person.TenantID
+	}
+
+	ps.events.publish(&PersonEvent{Action: action, PersonID: id, TenantID: tenantID, Person: person})
+}
+
+// loadAndAuthorizeOwner load person owner and check caller may modify the record
+func (ps *PersonService) loadAndAuthorizeOwner(ctx context.Context, q *persons.Queries, tenantID string, id int64) error {
+
+	sqlPerson, err := q.ReadPerson(ctx, &persons.ReadPersonParams{ID: id, TenantID: tenantID})
+	if err != nil {
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("error executing read person query %v", err)
+	}
+
+	return checkOwner(ctx, sqlPerson.Owner)
+}
+
+// checkOwner only admins and the owner may modify a person record
+func checkOwner(ctx context.Context, owner string) error {
+
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ErrForbidden
+	}
+
+	if principal.HasRole(RoleAdmin) || (owner != "" && principal.Subject == owner) {
+		return nil
+	}
+
+	return ErrForbidden
+}
+
+// actor subject of the caller, empty when not called on behalf of a principal
+func actor(ctx context.Context) string {
+
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return principal.Subject
+}
+
+// withTx execute fn within a single database transaction scoped to the context tenant
+func (ps *PersonService) withTx(ctx context.Context, fn func(q *persons.Queries, tenantID string) error) error {
+	return ps.inTx(ctx, func(tx *sql.Tx, tenantID string) error {
+		return fn(persons.New(tracing.WrapDBTX(tx)), tenantID)
+	})
+}
+
+// inTx run fn in a transaction spanning repositories of several tables
+func (ps *PersonService) inTx(ctx context.Context, fn func(tx *sql.Tx, tenantID string) error) error {
+
+	tenantID, err := tenant(ctx)
+	if err != nil {
+		return err
+	}
+
 	conn, err := ps.db.Conn(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get database connection %v", err)
 	}
 	defer func() { _ = conn.Close() }()
 
-	result, err := persons.New(conn).DeletePerson(ctx, id)
+	tx, err := conn.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("error excuting delete person query %v", err)
+		return fmt.Errorf("failed to begin transaction %v", err)
 	}
 
-	affected, err := result.RowsAffected()
+	err = fn(tx, tenantID)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected %v", err)
+		_ = tx.Rollback()
+		return err
 	}
 
-	if affected == 0 {
-		return ErrNotFound
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction %v", err)
 	}
 
 	return nil
@@ -195,6 +570,8 @@ func transformSQLPerson(sqlPerson *persons.Person) *Person {
 	var person Person
 
 	person.ID = sqlPerson.ID
+	person.TenantID = sqlPerson.TenantID
+	person.Owner = sqlPerson.Owner
 	person.FirstName = sqlPerson.Fname
 	person.LastName = sqlPerson.Lname
 	person.Email = sqlPerson.Email
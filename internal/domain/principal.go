@@ -0,0 +1,52 @@
+package domain
+
+import "context"
+
+const (
+	// RoleAdmin role granting administrative access
+	RoleAdmin = "admin"
+)
+
+// Principal authenticated caller of the service
+type Principal struct {
+	// Subject unique identifier of the caller
+	Subject string
+	// TenantID tenant the caller belongs to, empty if not bound to a tenant
+	TenantID string
+	// Roles coarse grained roles granted to the caller
+	Roles []string
+	// Scopes fine grained permissions granted to the caller
+	Scopes []string
+	// Method authentication method used to identify the caller
+	Method string
+}
+
+// ScopeAuthorizer decide if a principal was granted a scope, implemented by the auth policy
+type ScopeAuthorizer interface {
+	Allows(principal *Principal, scope string) bool
+}
+
+type principalKey struct{}
+
+// WithPrincipal attach authenticated principal to context
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext retrieve authenticated principal from context
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// HasRole check if principal was granted role
+func (p *Principal) HasRole(role string) bool {
+
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
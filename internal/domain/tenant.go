@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNoTenant service level error message when request is not scoped to a tenant
+	ErrNoTenant = errors.New("tenant not resolved")
+)
+
+type tenantKey struct{}
+
+// WithTenant scope context to tenant id
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext retrieve tenant id from context
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// tenant resolve tenant id required by every query
+func tenant(ctx context.Context) (string, error) {
+
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok {
+		return "", ErrNoTenant
+	}
+
+	return tenantID, nil
+}
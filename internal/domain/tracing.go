@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/trevatk/go-template/internal/domain"
+
+// startSpan start span for service method using the current global tracer provider
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name)
+}
+
+// spanError record err on span and return it unchanged, expected outcomes such as
+// missing records are recorded without marking the span as failed
+func spanError(span trace.Span, err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	span.RecordError(err)
+
+	if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrForbidden) && !errors.Is(err, ErrNoTenant) {
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,40 @@
+// Package features runtime feature flags
+package features
+
+import (
+	"sync/atomic"
+)
+
+// ReadOnly reject changes to persons while enabled, e.g. during database maintenance
+const ReadOnly = "read_only"
+
+// Config feature flag states by name
+type Config map[string]bool
+
+// Flags feature flags which can be replaced at runtime
+type Flags struct {
+	flags atomic.Pointer[Config]
+}
+
+// NewFlags create new feature flags instance
+func NewFlags(cfg Config) *Flags {
+	f := &Flags{}
+	f.Set(cfg)
+	return f
+}
+
+// Set replace all flag states
+func (f *Flags) Set(cfg Config) {
+
+	copied := make(Config, len(cfg))
+	for name, enabled := range cfg {
+		copied[name] = enabled
+	}
+
+	f.flags.Store(&copied)
+}
+
+// Enabled check if flag is enabled, unknown flags are disabled
+func (f *Flags) Enabled(name string) bool {
+	return (*f.flags.Load())[name]
+}
@@ -0,0 +1,17 @@
+package graph
+
+import (
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// MarshalID serialize int64 ids as strings as the ID scalar requires
+func MarshalID(id int64) graphql.Marshaler {
+	return graphql.MarshalString(strconv.FormatInt(id, 10))
+}
+
+// UnmarshalID accept ids as strings or integers
+func UnmarshalID(v interface{}) (int64, error) {
+	return graphql.UnmarshalInt64(v)
+}
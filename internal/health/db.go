@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/trevatk/go-template/internal/db"
+)
+
+// NewDatabaseCheck readiness check pinging the sqlite handle
+func NewDatabaseCheck(sqlite *sql.DB) Check {
+	return Check{
+		Name:     "sqlite",
+		Probe:    Readiness,
+		Critical: true,
+		Func: func(ctx context.Context) error {
+			if err := sqlite.PingContext(ctx); err != nil {
+				return fmt.Errorf("failed to ping database %v", err)
+			}
+			return nil
+		},
+	}
+}
+
+// NewMigrationCheck readiness check verifying all migrations of the migrations directory are applied
+func NewMigrationCheck(sqlite *sql.DB, cfg *db.Config) Check {
+	return Check{
+		Name:     "migrations",
+		Probe:    Readiness,
+		Critical: true,
+		Func: func(ctx context.Context) error {
+			return db.CheckMigrations(ctx, sqlite, cfg.MigrationsDir)
+		},
+	}
+}
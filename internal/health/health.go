@@ -0,0 +1,223 @@
+// Package health service liveness, readiness and startup probes
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+// Probe kind of probe a check contributes to
+type Probe string
+
+const (
+	// Liveness process is running and able to serve, failing restarts the service
+	Liveness Probe = "liveness"
+	// Readiness service can take traffic, failing removes it from load balancing
+	Readiness Probe = "readiness"
+	// Startup service completed initialization
+	Startup Probe = "startup"
+)
+
+const (
+	// StatusOK all checks passed
+	StatusOK = "ok"
+	// StatusDegraded only non critical checks failed, service keeps serving
+	StatusDegraded = "degraded"
+	// StatusFail at least one critical check failed
+	StatusFail = "fail"
+)
+
+// CheckGroup fx value group checks are provided into
+const CheckGroup = `group:"health_checks"`
+
+// checkTimeout upper bound of a single check
+const checkTimeout = time.Second * 2
+
+var (
+	// ErrNotStarted startup has not completed yet
+	ErrNotStarted = errors.New("startup not completed")
+	// ErrShuttingDown service is draining and no longer takes traffic
+	ErrShuttingDown = errors.New("shutting down")
+)
+
+// Check single dependency check
+type Check struct {
+	// Name unique name reported in the response body
+	Name string
+	// Probe probe the check contributes to
+	Probe Probe
+	// Critical failing check fails the probe, otherwise the probe is only degraded
+	Critical bool
+	// Func returns nil when the dependency is healthy
+	Func func(ctx context.Context) error
+}
+
+// CheckResult outcome of a single check
+type CheckResult struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Report probe response body
+type Report struct {
+	Status string                  `json:"status"`
+	Checks map[string]*CheckResult `json:"checks"`
+}
+
+// RegistryParams checks contributed by services through the health checks group
+type RegistryParams struct {
+	fx.In
+
+	Checks []Check `group:"health_checks"`
+}
+
+// AsCheck annotate constructor so its result is provided into the health checks group
+func AsCheck(constructor interface{}) interface{} {
+	return fx.Annotate(constructor, fx.ResultTags(CheckGroup))
+}
+
+// Registry pluggable registry of probe checks
+type Registry struct {
+	mu       sync.RWMutex
+	checks   map[Probe][]Check
+	started  atomic.Bool
+	draining atomic.Bool
+}
+
+// NewRegistry create new registry instance with all checks of the health checks group
+func NewRegistry(params RegistryParams) *Registry {
+
+	registry := &Registry{checks: map[Probe][]Check{}}
+
+	for _, c := range params.Checks {
+		registry.Register(c)
+	}
+
+	return registry
+}
+
+// Register add check to its probe
+func (hr *Registry) Register(check Check) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+
+	hr.checks[check.Probe] = append(hr.checks[check.Probe], check)
+}
+
+// MarkStarted report startup completed, startup probe fails until called
+func (hr *Registry) MarkStarted() {
+	hr.started.Store(true)
+}
+
+// Drain report shutdown in progress, readiness probe fails from now on
+func (hr *Registry) Drain() {
+	hr.draining.Store(true)
+}
+
+// Run execute all checks of probe concurrently
+func (hr *Registry) Run(ctx context.Context, probe Probe) *Report {
+
+	hr.mu.RLock()
+	checks := append([]Check{}, hr.checks[probe]...)
+	hr.mu.RUnlock()
+
+	if probe == Startup {
+		checks = append(checks, Check{Name: "startup", Critical: true, Func: hr.startupCompleted})
+	}
+
+	if probe == Readiness {
+		checks = append(checks, Check{Name: "shutdown", Critical: true, Func: hr.notDraining})
+	}
+
+	results := make([]*CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			results[i] = run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]*CheckResult, len(checks))}
+
+	for i, c := range checks {
+
+		report.Checks[c.Name] = results[i]
+
+		if results[i].Status == StatusOK {
+			continue
+		}
+
+		if c.Critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// Handler serve probe as json, failing probes respond 503
+func (hr *Registry) Handler(probe Probe) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		report := hr.Run(r.Context(), probe)
+
+		status := http.StatusOK
+		if report.Status == StatusFail {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+func (hr *Registry) startupCompleted(_ context.Context) error {
+
+	if !hr.started.Load() {
+		return ErrNotStarted
+	}
+
+	return nil
+}
+
+func (hr *Registry) notDraining(_ context.Context) error {
+
+	if hr.draining.Load() {
+		return ErrShuttingDown
+	}
+
+	return nil
+}
+
+func run(ctx context.Context, c Check) *CheckResult {
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Func(ctx)
+
+	result := &CheckResult{Status: StatusOK, Latency: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,52 @@
+// Package lifecycle service background work tracking
+package lifecycle
+
+import (
+	"context"
+	"sync"
+)
+
+// Workers tracks background work which must finish before shutdown completes
+type Workers struct {
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewWorkers create new workers instance
+func NewWorkers() *Workers {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Workers{ctx: ctx, cancel: cancel}
+}
+
+// Go run fn in the background, its context is cancelled once shutdown begins
+func (w *Workers) Go(fn func(ctx context.Context)) {
+
+	w.wg.Add(1)
+
+	go func() {
+		defer w.wg.Done()
+		fn(w.ctx)
+	}()
+}
+
+// Wait signal shutdown to all workers and block until they returned or ctx expires
+func (w *Workers) Wait(ctx context.Context) error {
+
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,26 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type loggerKey struct{}
+
+// WithContext attach request scoped logger to context
+func WithContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext retrieve request scoped logger from context, falls back to the global logger
+// the service installs on startup
+func FromContext(ctx context.Context) *zap.Logger {
+
+	logger, ok := ctx.Value(loggerKey{}).(*zap.Logger)
+	if !ok || logger == nil {
+		return zap.L()
+	}
+
+	return logger
+}
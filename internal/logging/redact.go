@@ -0,0 +1,176 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replacement of masked values
+const Redacted = "[redacted]"
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+// redactCore zapcore.Core wrapper masking sensitive fields and email addresses
+// before entries reach the wrapped core
+type redactCore struct {
+	zapcore.Core
+	fields map[string]struct{}
+}
+
+// NewRedactCore wrap core so that values of fields (matched case insensitive, also
+// when nested in objects) and anything resembling an email address are masked
+func NewRedactCore(core zapcore.Core, fields []string) zapcore.Core {
+
+	set := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			set[f] = struct{}{}
+		}
+	}
+
+	return &redactCore{Core: core, fields: set}
+}
+
+// With redact context fields before they are encoded by the wrapped core
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.redactFields(fields)), fields: c.fields}
+}
+
+// Check route enabled entries through this core so Write can redact them
+func (c *redactCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+
+	return ce
+}
+
+// Write redact message and fields then write to wrapped core
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = redactString(entry.Message)
+	return c.Core.Write(entry, c.redactFields(fields))
+}
+
+func (c *redactCore) redactFields(fields []zapcore.Field) []zapcore.Field {
+
+	redacted := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		redacted = append(redacted, c.redactField(f))
+	}
+
+	return redacted
+}
+
+func (c *redactCore) redactField(f zapcore.Field) zapcore.Field {
+
+	if c.sensitive(f.Key) {
+		return zap.String(f.Key, Redacted)
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = redactString(f.String)
+		return f
+	case zapcore.ByteStringType:
+		return zap.String(f.Key, redactString(string(f.Interface.([]byte))))
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return redactCall(f, err.Error)
+		}
+		return f
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok && s != nil {
+			return redactCall(f, s.String)
+		}
+		return f
+	case zapcore.ObjectMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		if err := f.Interface.(zapcore.ObjectMarshaler).MarshalLogObject(enc); err != nil {
+			return zap.String(f.Key, Redacted)
+		}
+		return zap.Any(f.Key, c.redactValue(enc.Fields))
+	case zapcore.ArrayMarshalerType, zapcore.ReflectType:
+		v, err := genericValue(f)
+		if err != nil {
+			return zap.String(f.Key, Redacted)
+		}
+		return zap.Any(f.Key, c.redactValue(v))
+	case zapcore.NamespaceType, zapcore.SkipType:
+		return f
+	default:
+		// numeric, bool, time and duration values cannot carry email addresses
+		return f
+	}
+}
+
+// redactCall redact string returned by value, e.g. String of a Stringer field. Panics such as
+// nil pointer receivers leave f to zap, which recovers and encodes nil pointers as <nil>
+func redactCall(f zapcore.Field, value func() string) (redacted zapcore.Field) {
+
+	defer func() {
+		if recover() != nil {
+			redacted = f
+		}
+	}()
+
+	return zap.String(f.Key, redactString(value()))
+}
+
+// redactValue walk generic value masking sensitive keys and strings
+func (c *redactCore) redactValue(v interface{}) interface{} {
+
+	switch t := v.(type) {
+	case string:
+		return redactString(t)
+	case map[string]interface{}:
+		for k, inner := range t {
+			if c.sensitive(k) {
+				t[k] = Redacted
+				continue
+			}
+			t[k] = c.redactValue(inner)
+		}
+		return t
+	case []interface{}:
+		for i, inner := range t {
+			t[i] = c.redactValue(inner)
+		}
+		return t
+	default:
+		return t
+	}
+}
+
+func (c *redactCore) sensitive(key string) bool {
+	_, ok := c.fields[strings.ToLower(key)]
+	return ok
+}
+
+// genericValue convert arbitrary field into maps, slices and scalars by a json round trip
+func genericValue(f zapcore.Field) (interface{}, error) {
+
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+
+	b, err := json.Marshal(enc.Fields[f.Key])
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func redactString(s string) string {
+	return emailPattern.ReplaceAllString(s, Redacted)
+}
@@ -0,0 +1,123 @@
+package logging_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/logging"
+)
+
+const email = "jane.doe@mailbox.com"
+
+type credentials struct {
+	user     string
+	password string
+}
+
+func (c credentials) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user", c.user)
+	enc.AddString("password", c.password)
+	return nil
+}
+
+type contact struct {
+	email string
+}
+
+func (c *contact) String() string {
+	return "contact " + c.email
+}
+
+func TestRedactCore(t *testing.T) {
+
+	assert := assert.New(t)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(logging.NewRedactCore(core, []string{"password", "Authorization"}))
+
+	newPerson := &domain.NewPerson{FirstName: "jane", LastName: "doe", Email: email}
+
+	logger.With(zap.String("contact", email)).Info("person "+email,
+		zap.Any("person", newPerson),
+		zap.Error(fmt.Errorf("failed to insert %s: %w", email, errors.New("constraint failed"))),
+		zap.ByteString("body", []byte(`{"email":"`+email+`"}`)),
+		zap.Object("credentials", credentials{user: email, password: "hunter2"}),
+		zap.Strings("recipients", []string{"ops", email}),
+		zap.String("authorization", "Bearer secret"),
+		zap.Int("attempt", 3),
+	)
+
+	logger.Sugar().Errorf("unable to create new person %+v", newPerson)
+
+	entries := logs.All()
+	assert.Len(entries, 2)
+
+	for _, entry := range entries {
+		assert.NotContains(entry.Message, email)
+		assert.NotContains(fmt.Sprint(entry.ContextMap()), email)
+	}
+
+	fields := entries[0].ContextMap()
+	assert.Equal("person "+logging.Redacted, entries[0].Message)
+	assert.Equal(logging.Redacted, fields["contact"])
+	assert.Equal("failed to insert [redacted]: constraint failed", fields["error"])
+	assert.Equal(logging.Redacted, fields["authorization"])
+	assert.Equal(map[string]interface{}{"user": logging.Redacted, "password": logging.Redacted}, fields["credentials"])
+	assert.Equal([]interface{}{"ops", logging.Redacted}, fields["recipients"])
+	assert.Equal(int64(3), fields["attempt"])
+	assert.NotContains(fmt.Sprint(fields), "hunter2")
+	assert.NotContains(fmt.Sprint(fields), "secret")
+}
+
+func TestRedactNilStringer(t *testing.T) {
+
+	assert := assert.New(t)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(logging.NewRedactCore(core, nil))
+
+	var missing *contact
+
+	assert.NotPanics(func() {
+		logger.Info("stringers",
+			zap.Stringer("missing", missing),
+			zap.Stringer("present", &contact{email: email}),
+		)
+	})
+
+	if assert.Len(logs.All(), 1) {
+		fields := logs.All()[0].ContextMap()
+		assert.Equal("<nil>", fields["missing"])
+		assert.Equal("contact "+logging.Redacted, fields["present"])
+	}
+}
+
+func TestNewRedacts(t *testing.T) {
+
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "service.log")
+
+	cfg := logging.DefaultConfig()
+	cfg.Outputs = []string{path}
+
+	logger, _, err := logging.New(cfg)
+	assert.NoError(err)
+
+	logger.Info("create person", zap.Any("person", &domain.NewPerson{FirstName: "jane", LastName: "doe", Email: email}))
+	assert.NoError(logger.Sync())
+
+	b, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.Contains(string(b), "jane")
+	assert.NotContains(string(b), email)
+}
@@ -2,18 +2,163 @@
 package logging
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// New create new uber/zap logger instance
-func New() (*zap.Logger, error) {
+// Config logger configuration
+type Config struct {
+	// Format log encoding, json or console
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+	// Level minimum enabled level, adjustable at runtime
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+	// SamplingInitial entries logged per second for identical messages before sampling, 0 disables sampling
+	SamplingInitial int `yaml:"sampling_initial" env:"LOG_SAMPLING_INITIAL"`
+	// SamplingThereafter log every nth identical message once sampling kicked in
+	SamplingThereafter int `yaml:"sampling_thereafter" env:"LOG_SAMPLING_THEREAFTER"`
+	// Outputs stdout, stderr or file paths, files are rotated
+	Outputs []string `yaml:"outputs" env:"LOG_OUTPUT"`
+	// Caller annotate entries with calling function
+	Caller bool `yaml:"caller" env:"LOG_CALLER"`
+	// StacktraceLevel minimum level at which stacktraces are captured
+	StacktraceLevel string `yaml:"stacktrace_level" env:"LOG_STACKTRACE_LEVEL"`
+	// Rotation applied to file outputs
+	Rotation Rotation `yaml:"rotation"`
+	// RedactFields field names whose values are masked, email addresses are always masked
+	RedactFields []string `yaml:"redact_fields" env:"LOG_REDACT_FIELDS"`
+}
+
+// Rotation file output rotation policy
+type Rotation struct {
+	MaxSizeMB  int  `yaml:"max_size_mb" env:"LOG_ROTATE_MAX_SIZE_MB"`
+	MaxBackups int  `yaml:"max_backups" env:"LOG_ROTATE_MAX_BACKUPS"`
+	MaxAgeDays int  `yaml:"max_age_days" env:"LOG_ROTATE_MAX_AGE_DAYS"`
+	Compress   bool `yaml:"compress" env:"LOG_ROTATE_COMPRESS"`
+}
+
+// DefaultConfig production defaults, json encoded info level logs written to stderr
+func DefaultConfig() *Config {
+	return &Config{
+		Format:             "json",
+		Level:              "info",
+		SamplingInitial:    100,
+		SamplingThereafter: 100,
+		Outputs:            []string{"stderr"},
+		Caller:             true,
+		StacktraceLevel:    "error",
+		Rotation: Rotation{
+			MaxSizeMB:  100,
+			MaxBackups: 5,
+			MaxAgeDays: 30,
+			Compress:   true,
+		},
+		RedactFields: []string{"email", "password", "authorization", "token", "api_key"},
+	}
+}
+
+// Validate check configuration values
+func (c *Config) Validate() error {
+
+	var errs []error
+
+	if c.Format != "json" && c.Format != "console" {
+		errs = append(errs, fmt.Errorf("unsupported log format %q", c.Format))
+	}
+
+	if _, err := zapcore.ParseLevel(c.Level); err != nil {
+		errs = append(errs, fmt.Errorf("invalid log level %q", c.Level))
+	}
+
+	if _, err := zapcore.ParseLevel(c.StacktraceLevel); err != nil {
+		errs = append(errs, fmt.Errorf("invalid stacktrace level %q", c.StacktraceLevel))
+	}
+
+	if len(c.Outputs) == 0 {
+		errs = append(errs, errors.New("at least one log output is required"))
+	}
+
+	if c.SamplingInitial < 0 || c.SamplingThereafter < 0 {
+		errs = append(errs, errors.New("sampling must not be negative"))
+	}
+
+	if c.Rotation.MaxSizeMB < 0 || c.Rotation.MaxBackups < 0 || c.Rotation.MaxAgeDays < 0 {
+		errs = append(errs, errors.New("rotation limits must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
 
-	logger, err := zap.NewDevelopment()
+// New create new uber/zap logger instance and the atomic level controlling it
+func New(cfg *Config) (*zap.Logger, zap.AtomicLevel, error) {
+
+	if err := cfg.Validate(); err != nil {
+		return nil, zap.AtomicLevel{}, err
+	}
+
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, zap.AtomicLevel{}, fmt.Errorf("unable to parse log level %v", err)
+	}
+
+	stacktraceLevel, err := zapcore.ParseLevel(cfg.StacktraceLevel)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create uber/zap development logger %v", err)
+		return nil, zap.AtomicLevel{}, fmt.Errorf("unable to parse stacktrace level %v", err)
 	}
 
-	return logger, nil
+	var encoder zapcore.Encoder
+	if cfg.Format == "console" {
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	} else {
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+
+	syncers := make([]zapcore.WriteSyncer, 0, len(cfg.Outputs))
+	for _, output := range cfg.Outputs {
+		syncers = append(syncers, writeSyncer(strings.TrimSpace(output), cfg.Rotation))
+	}
+
+	core := NewRedactCore(zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(syncers...), level), cfg.RedactFields)
+
+	if cfg.SamplingInitial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.SamplingInitial, cfg.SamplingThereafter)
+	}
+
+	options := []zap.Option{
+		zap.AddStacktrace(stacktraceLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+	}
+
+	if cfg.Caller {
+		options = append(options, zap.AddCaller())
+	}
+
+	return zap.New(core, options...), level, nil
+}
+
+// writeSyncer standard streams are written directly, anything else is a rotated file
+func writeSyncer(output string, rotation Rotation) zapcore.WriteSyncer {
+
+	switch output {
+	case "stdout":
+		return zapcore.Lock(os.Stdout)
+	case "stderr":
+		return zapcore.Lock(os.Stderr)
+	default:
+		return zapcore.AddSync(&lumberjack.Logger{
+			Filename:   output,
+			MaxSize:    rotation.MaxSizeMB,
+			MaxBackups: rotation.MaxBackups,
+			MaxAge:     rotation.MaxAgeDays,
+			Compress:   rotation.Compress,
+		})
+	}
 }
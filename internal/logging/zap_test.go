@@ -0,0 +1,53 @@
+package logging_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/trevatk/go-template/internal/logging"
+)
+
+func TestValidate(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.NoError(logging.DefaultConfig().Validate())
+
+	cfg := logging.DefaultConfig()
+	cfg.Format = "xml"
+	cfg.Level = "verbose"
+	cfg.Rotation.MaxSizeMB = -1
+
+	// all invalid values are reported
+	err := cfg.Validate()
+	assert.ErrorContains(err, "xml")
+	assert.ErrorContains(err, "verbose")
+	assert.ErrorContains(err, "rotation")
+}
+
+func TestNew(t *testing.T) {
+
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "service.log")
+
+	cfg := logging.DefaultConfig()
+	cfg.Outputs = []string{path}
+
+	logger, level, err := logging.New(cfg)
+	assert.NoError(err)
+
+	logger.Debug("discarded")
+	level.SetLevel(zapcore.DebugLevel)
+	logger.Debug("written")
+	assert.NoError(logger.Sync())
+
+	b, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.NotContains(string(b), "discarded")
+	assert.Contains(string(b), `"msg":"written"`)
+}
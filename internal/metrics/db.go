@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/trevatk/go-template/internal/db"
+)
+
+// collectTimeout bound of queries run on scrape
+const collectTimeout = time.Second * 5
+
+// NewDBStatsCollector create collector exposing connection pool stats of the sqlite handle
+func NewDBStatsCollector(sqlite *sql.DB) prometheus.Collector {
+	return collectors.NewDBStatsCollector(sqlite, "sqlite")
+}
+
+// MigrationCollector exposes applied schema migration version
+type MigrationCollector struct {
+	db      *sql.DB
+	version *prometheus.Desc
+	dirty   *prometheus.Desc
+}
+
+// NewMigrationCollector create new migration collector instance
+func NewMigrationCollector(sqlite *sql.DB) *MigrationCollector {
+	return &MigrationCollector{
+		db: sqlite,
+		version: prometheus.NewDesc(
+			"db_migration_version",
+			"Currently applied schema migration version.",
+			nil, nil,
+		),
+		dirty: prometheus.NewDesc(
+			"db_migration_dirty",
+			"Whether the last schema migration failed part way (1) or not (0).",
+			nil, nil,
+		),
+	}
+}
+
+// Describe implement prometheus.Collector
+func (mc *MigrationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- mc.version
+	ch <- mc.dirty
+}
+
+// Collect implement prometheus.Collector, version is read on every scrape
+func (mc *MigrationCollector) Collect(ch chan<- prometheus.Metric) {
+
+	// collectors are not given the scrape context, bound the query instead
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	version, dirty, err := db.Version(ctx, mc.db)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(mc.version, err)
+		return
+	}
+
+	var d float64
+	if dirty {
+		d = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(mc.version, prometheus.GaugeValue, float64(version))
+	ch <- prometheus.MustNewConstMetric(mc.dirty, prometheus.GaugeValue, d)
+}
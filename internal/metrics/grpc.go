@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// GRPCMetrics rpc counter and latency histogram labelled by full method name
+type GRPCMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewGRPCMetrics create new grpc metrics instance
+func NewGRPCMetrics() *GRPCMetrics {
+	return &GRPCMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_requests_total",
+			Help: "Total number of grpc calls by method and status code.",
+		}, []string{"method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_request_duration_seconds",
+			Help:    "Latency of grpc calls by method and status code, streams are observed once closed.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+	}
+}
+
+// Observe record completed call
+func (m *GRPCMetrics) Observe(method, code string, latency time.Duration) {
+	m.requests.WithLabelValues(method, code).Inc()
+	m.duration.WithLabelValues(method, code).Observe(latency.Seconds())
+}
+
+// Describe implement prometheus.Collector
+func (m *GRPCMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.duration.Describe(ch)
+}
+
+// Collect implement prometheus.Collector
+func (m *GRPCMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.duration.Collect(ch)
+}
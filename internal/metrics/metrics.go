@@ -0,0 +1,50 @@
+// Package metrics service prometheus metrics
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.uber.org/fx"
+)
+
+// CollectorGroup fx value group every collector exposed on /metrics is provided into
+const CollectorGroup = `group:"collectors"`
+
+// RegistryParams collectors contributed by services through the collectors group
+type RegistryParams struct {
+	fx.In
+
+	Collectors []prometheus.Collector `group:"collectors"`
+}
+
+// NewRegistry create new prometheus registry with go runtime, process and build info
+// collectors in addition to all collectors of the collectors group
+func NewRegistry(params RegistryParams) (*prometheus.Registry, error) {
+
+	registry := prometheus.NewRegistry()
+
+	defaults := []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewBuildInfoCollector(),
+	}
+
+	for _, c := range append(defaults, params.Collectors...) {
+		if err := registry.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register collector %v", err)
+		}
+	}
+
+	return registry, nil
+}
+
+// AsCollector annotate constructor so its result is provided into the collectors group
+func AsCollector(constructor interface{}) interface{} {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(prometheus.Collector)),
+		fx.ResultTags(CollectorGroup),
+	)
+}
@@ -0,0 +1,117 @@
+package port
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/logging"
+)
+
+const (
+	// RequestIDHeader request header used to correlate requests across services
+	RequestIDHeader = "X-Request-ID"
+)
+
+var (
+	requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+)
+
+type requestIDKey struct{}
+
+// RequestIDFromContext retrieve request id assigned by the http port
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// accessEntry request details collected by inner middleware for the access log
+type accessEntry struct {
+	principal string
+}
+
+type accessEntryKey struct{}
+
+// recordPrincipal make authenticated principal available to the access log
+func recordPrincipal(ctx context.Context, principal *domain.Principal) {
+	if entry, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+		entry.principal = principal.Subject
+	}
+}
+
+// requestID middleware propagating or assigning a request id and attaching a
+// request scoped logger to the context
+func requestID(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			id := r.Header.Get(RequestIDHeader)
+			if !requestIDPattern.MatchString(id) {
+				id = newRequestID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			ctx = logging.WithContext(ctx, logger.With(zap.String("request_id", id)))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// accessLog middleware emitting one structured log line per request
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		start := time.Now()
+
+		entry := &accessEntry{}
+		r = r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry))
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		logging.FromContext(r.Context()).Named("access").Info("request",
+			zap.String("method", r.Method),
+			zap.String("route", routePattern(r)),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", status),
+			zap.Int("bytes", ww.BytesWritten()),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("principal", entry.principal),
+			zap.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
+
+// routePattern matched chi route pattern, empty when no route matched
+func routePattern(r *http.Request) string {
+
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+
+	return rctx.RoutePattern()
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
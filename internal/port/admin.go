@@ -0,0 +1,247 @@
+package port
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/trevatk/go-template/internal/auth"
+	"github.com/trevatk/go-template/internal/db"
+	"github.com/trevatk/go-template/internal/health"
+	"github.com/trevatk/go-template/internal/logging"
+)
+
+// AdminConfig admin listener configuration
+type AdminConfig struct {
+	// Port tcp port of the admin listener
+	Port int `yaml:"port" env:"ADMIN_PORT"`
+	// LocalOnly bind the admin listener to localhost only, otherwise the http tls settings
+	// are required and metrics and profiles are authenticated
+	LocalOnly bool `yaml:"local_only" env:"ADMIN_LOCAL_ONLY"`
+	// ReadTimeout maximum duration for reading an entire request
+	ReadTimeout time.Duration `yaml:"read_timeout" env:"ADMIN_READ_TIMEOUT"`
+	// WriteTimeout maximum duration before timing out writes of the response, covers cpu profiles
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"ADMIN_WRITE_TIMEOUT"`
+}
+
+// DefaultAdminConfig admin listener defaults, reachable from localhost only
+func DefaultAdminConfig() *AdminConfig {
+	return &AdminConfig{
+		Port:         9090,
+		LocalOnly:    true,
+		ReadTimeout:  time.Second * 15,
+		WriteTimeout: time.Minute,
+	}
+}
+
+// Validate check configuration values
+func (c *AdminConfig) Validate() error {
+
+	var errs []error
+
+	if c.Port < 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid port %d", c.Port))
+	}
+
+	if c.ReadTimeout <= 0 || c.WriteTimeout <= 0 {
+		errs = append(errs, errors.New("timeouts must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Addr listen address of the admin listener
+func (c *AdminConfig) Addr() string {
+
+	host := ""
+	if c.LocalOnly {
+		host = "127.0.0.1"
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(c.Port))
+}
+
+// NewAdminRouter chi router of the admin listener serving metrics, probes, profiles
+// and authenticated admin actions
+func NewAdminRouter(
+	cfg *AdminConfig,
+	logger *zap.Logger,
+	policy *auth.Policy,
+	jwtVerifier *auth.JWTVerifier,
+	apiKeyAuthenticator *auth.APIKeyAuthenticator,
+	certificateAuthenticator *auth.CertificateAuthenticator,
+	logLevel zap.AtomicLevel,
+	registry *prometheus.Registry,
+	healthRegistry *health.Registry,
+	sqlite *sql.DB,
+	sqliteCfg *db.Config,
+) *chi.Mux {
+
+	r := chi.NewRouter()
+
+	r.Use(requestID(logger.Named("admin server")))
+	r.Use(accessLog)
+
+	authn := authenticate(jwtVerifier, apiKeyAuthenticator, certificateAuthenticator)
+
+	r.Method(http.MethodGet, "/livez", healthRegistry.Handler(health.Liveness))
+	r.Method(http.MethodGet, "/readyz", healthRegistry.Handler(health.Readiness))
+	r.Method(http.MethodGet, "/startupz", healthRegistry.Handler(health.Startup))
+
+	r.Group(func(r chi.Router) {
+
+		// reachable beyond localhost metrics and profiles are limited to operators
+		if !cfg.LocalOnly {
+			r.Use(authn, authorize(policy, auth.ScopeMetricsRead))
+		}
+
+		r.Method(http.MethodGet, "/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	})
+
+	r.Group(func(r chi.Router) {
+
+		if !cfg.LocalOnly {
+			r.Use(authn, authorize(policy, auth.ScopeProfileAdmin))
+		}
+
+		r.Mount("/debug", middleware.Profiler())
+	})
+
+	r.Route("/admin", func(r chi.Router) {
+
+		r.Use(authn)
+
+		// zap atomic level handler, GET reports and PUT {"level":"debug"} changes the level
+		r.With(authorize(policy, auth.ScopeLogAdmin)).Method(http.MethodGet, "/log/level", logLevel)
+		r.With(authorize(policy, auth.ScopeLogAdmin)).Method(http.MethodPut, "/log/level", logLevel)
+
+		r.With(authorize(policy, auth.ScopeBackupAdmin)).Post("/backup", backup(sqlite, sqliteCfg.BackupDir))
+	})
+
+	return r
+}
+
+// backup handler writing a database backup into dir
+func backup(sqlite *sql.DB, dir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		log := logging.FromContext(r.Context()).Sugar()
+
+		start := time.Now()
+
+		path, err := db.Backup(r.Context(), sqlite, dir)
+		if err != nil {
+			log.Errorf("unable to backup database %v", err)
+			http.Error(w, "unable to backup database", http.StatusInternalServerError)
+			return
+		}
+
+		log.Infof("database backup written to %s", path)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(map[string]string{
+			"path":     path,
+			"duration": time.Since(start).String(),
+		}); err != nil {
+			log.Errorf("failed to encode response %v", err)
+		}
+	}
+}
+
+// AdminServer http server of the admin listener
+type AdminServer struct {
+	srv *http.Server
+}
+
+// NewAdminServer create new admin server instance, refuses to listen beyond localhost
+// in plaintext. Tls follows the http tls settings.
+func NewAdminServer(logger *zap.Logger, handler http.Handler, cfg *AdminConfig, serverCfg *ServerConfig) (*AdminServer, error) {
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !cfg.LocalOnly && !serverCfg.TLS.Enabled() {
+		return nil, errors.New("admin listener must be local only unless http tls is configured")
+	}
+
+	var tlsConfig *tls.Config
+	if !cfg.LocalOnly {
+
+		var err error
+		tlsConfig, err = NewTLSConfig(logger, &serverCfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	errorLog, err := zap.NewStdLogAt(logger.Named("admin server"), zap.WarnLevel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create error log %v", err)
+	}
+
+	return &AdminServer{
+		srv: &http.Server{
+			Addr:         cfg.Addr(),
+			Handler:      handler,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			TLSConfig:    tlsConfig,
+			ErrorLog:     errorLog,
+		},
+	}, nil
+}
+
+// Listen bind configured address
+func (s *AdminServer) Listen() (net.Listener, error) {
+
+	l, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s %v", s.srv.Addr, err)
+	}
+
+	return l, nil
+}
+
+// Serve accept connections on l until shutdown, a regular shutdown is not an error
+func (s *AdminServer) Serve(l net.Listener) error {
+
+	var err error
+	if s.srv.TLSConfig != nil {
+		err = s.srv.ServeTLS(l, "", "")
+	} else {
+		err = s.srv.Serve(l)
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown stop accepting connections and wait for in-flight requests, remaining
+// connections are closed when ctx expires
+func (s *AdminServer) Shutdown(ctx context.Context) error {
+
+	if err := s.srv.Shutdown(ctx); err != nil {
+		_ = s.srv.Close()
+		return fmt.Errorf("failed to shutdown admin server %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/trevatk/go-template/client"
+)
+
+func (suite *HTTPServerSuite) TestPersonClient() {
+
+	assert := assert.New(suite.T())
+
+	srv := httptest.NewServer(suite.mux)
+	defer srv.Close()
+
+	newClient := func(credentials client.Credentials) *client.PersonClient {
+
+		cfg := client.DefaultConfig(srv.URL)
+		cfg.Tenant = testTenant
+		cfg.Credentials = credentials
+
+		persons, err := client.NewPersonClient(cfg)
+		assert.NoError(err)
+
+		return persons
+	}
+
+	persons := newClient(client.BearerToken(validToken("unit-test")))
+
+	ctx := context.Background()
+
+	created, err := persons.Create(ctx, &client.NewPerson{FirstName: "sdk", LastName: "client", Email: "sdk.client@mailbox.com"})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("unit-test", created.Owner)
+	assert.Equal(testTenant, created.TenantID)
+	assert.True(created.UpdatedAt.IsZero())
+
+	fetched, err := persons.Get(ctx, created.ID)
+	assert.NoError(err)
+	assert.Equal(created.Email, fetched.Email)
+
+	updated, err := persons.Update(ctx, &client.UpdatePerson{ID: created.ID, FirstName: "sdk", LastName: "updated", Email: "sdk.client@mailbox.com"})
+	assert.NoError(err)
+	assert.Equal("updated", updated.LastName)
+	assert.False(updated.UpdatedAt.IsZero())
+
+	page, err := persons.List(ctx, &client.ListOptions{Limit: 1, After: created.ID - 1})
+	assert.NoError(err)
+	if assert.Len(page.Persons, 1) {
+		assert.Equal(created.ID, page.Persons[0].ID)
+	}
+
+	// structured errors decode to sentinel errors
+	_, err = persons.Get(ctx, created.ID+999)
+	assert.ErrorIs(err, client.ErrNotFound)
+
+	_, err = persons.Create(ctx, &client.NewPerson{FirstName: "sdk"})
+	assert.ErrorIs(err, client.ErrInvalid)
+
+	var apiErr *client.Error
+	if assert.True(errors.As(err, &apiErr)) {
+		assert.Contains(apiErr.Detail, "invalid request body")
+		assert.NotEmpty(apiErr.RequestID)
+	}
+
+	err = newClient(client.BearerToken(validToken("intruder"))).Delete(ctx, created.ID)
+	assert.ErrorIs(err, client.ErrForbidden)
+
+	_, err = newClient(nil).List(ctx, nil)
+	assert.ErrorIs(err, client.ErrUnauthorized)
+
+	assert.NoError(persons.Delete(ctx, created.ID))
+
+	_, err = persons.Get(ctx, created.ID)
+	assert.ErrorIs(err, client.ErrNotFound)
+}
@@ -0,0 +1,118 @@
+package port
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// CORSConfig cross origin resource sharing configuration
+type CORSConfig struct {
+	// AllowedOrigins origins allowed to call the api, * allows any origin
+	AllowedOrigins []string `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+}
+
+// DefaultCORSConfig cross origin requests are rejected unless origins are configured
+func DefaultCORSConfig() *CORSConfig {
+	return &CORSConfig{AllowedOrigins: []string{}}
+}
+
+// Validate check configuration values
+func (c *CORSConfig) Validate() error {
+	return nil
+}
+
+var (
+	corsMethods = strings.Join([]string{
+		http.MethodGet,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodDelete,
+	}, ", ")
+	corsHeaders = strings.Join([]string{
+		"Authorization",
+		"Content-Type",
+		IdempotencyKeyHeader,
+		RequestIDHeader,
+		TenantHeader,
+	}, ", ")
+	corsMaxAge = strconv.Itoa(int((time.Minute * 10).Seconds()))
+)
+
+// CORS cross origin middleware whose allowed origins can be replaced at runtime
+type CORS struct {
+	origins atomic.Pointer[map[string]struct{}]
+}
+
+// NewCORS create new cors instance
+func NewCORS(cfg *CORSConfig) *CORS {
+	c := &CORS{}
+	c.SetAllowedOrigins(cfg.AllowedOrigins)
+	return c
+}
+
+// SetAllowedOrigins replace allowed origins
+func (c *CORS) SetAllowedOrigins(origins []string) {
+
+	set := make(map[string]struct{}, len(origins))
+	for _, o := range origins {
+		set[strings.TrimSuffix(o, "/")] = struct{}{}
+	}
+
+	c.origins.Store(&set)
+}
+
+func (c *CORS) allowed(origin string) bool {
+
+	set := *c.origins.Load()
+
+	if _, ok := set["*"]; ok {
+		return true
+	}
+
+	_, ok := set[origin]
+	return ok
+}
+
+// Handler middleware answering preflight requests and annotating responses of allowed origins
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if !c.allowed(origin) {
+
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			// browser enforces the missing allow origin header
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join([]string{RequestIDHeader, "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"}, ", "))
+
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
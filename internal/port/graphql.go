@@ -0,0 +1,522 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/trevatk/go-template/internal/auth"
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/features"
+	"github.com/trevatk/go-template/internal/graph"
+	"github.com/trevatk/go-template/internal/logging"
+	"github.com/trevatk/go-template/internal/ratelimit"
+)
+
+const (
+	// graphQL error codes reported in the code extension of errors
+	codeNotFound      = "NOT_FOUND"
+	codeForbidden     = "FORBIDDEN"
+	codeBadUserInput  = "BAD_USER_INPUT"
+	codeRateLimited   = "RATE_LIMITED"
+	codeUnavailable   = "SERVICE_UNAVAILABLE"
+	codeDepthLimit    = "DEPTH_LIMIT_EXCEEDED"
+	codeInternalError = "INTERNAL_SERVER_ERROR"
+)
+
+// graphQLQueryCacheSize parsed and validated queries kept in memory
+const graphQLQueryCacheSize = 1000
+
+// GraphQLConfig graphql endpoint configuration
+type GraphQLConfig struct {
+	// MaxDepth deepest field nesting accepted, introspection fields are not counted
+	MaxDepth int `yaml:"max_depth" env:"GRAPHQL_MAX_DEPTH"`
+	// MaxComplexity highest operation complexity accepted, list fields cost their child
+	// complexity times the requested page size
+	MaxComplexity int `yaml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY"`
+	// Introspection serve schema introspection queries
+	Introspection bool `yaml:"introspection" env:"GRAPHQL_INTROSPECTION"`
+}
+
+// DefaultGraphQLConfig graphql endpoint defaults
+func DefaultGraphQLConfig() *GraphQLConfig {
+	return &GraphQLConfig{
+		MaxDepth:      10,
+		MaxComplexity: 1000,
+		Introspection: true,
+	}
+}
+
+// Validate check configuration values
+func (c *GraphQLConfig) Validate() error {
+
+	var errs []error
+
+	if c.MaxDepth <= 0 {
+		errs = append(errs, errors.New("max depth must be positive"))
+	}
+
+	if c.MaxComplexity <= 0 {
+		errs = append(errs, errors.New("max complexity must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// GraphQLHandler graphql transport of the person service
+type GraphQLHandler struct {
+	srv    *handler.Server
+	bundle *domain.Bundle
+}
+
+// NewGraphQLHandler create new graphql handler instance, expects authenticated requests
+// scoped to a tenant
+func NewGraphQLHandler(
+	bundle *domain.Bundle,
+	policy *auth.Policy,
+	limiter *ratelimit.Limiter,
+	flags *features.Flags,
+	cfg *GraphQLConfig,
+) (*GraphQLHandler, error) {
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	resolver := &graphResolver{bundle: bundle}
+
+	schemaConfig := graph.Config{Resolvers: resolver}
+	schemaConfig.Directives.Scope = scopeDirective(policy)
+	schemaConfig.Complexity.Query.Persons = func(childComplexity int, first *int, _ *int64) int {
+		return childComplexity * listSize(first)
+	}
+	schemaConfig.Complexity.Query.SearchPersons = func(childComplexity int, _ string, first *int, _ *int64) int {
+		return childComplexity * listSize(first)
+	}
+
+	srv := handler.New(graph.NewExecutableSchema(schemaConfig))
+
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+
+	srv.SetQueryCache(lru.New(graphQLQueryCacheSize))
+
+	if cfg.Introspection {
+		srv.Use(extension.Introspection{})
+	}
+
+	srv.Use(depthLimit{max: cfg.MaxDepth})
+	srv.Use(extension.FixedComplexityLimit(cfg.MaxComplexity))
+
+	srv.AroundOperations(mutationReadOnly(flags))
+	srv.AroundRootFields(mutationRateLimit(limiter))
+
+	srv.SetRecoverFunc(func(ctx context.Context, err interface{}) error {
+		logging.FromContext(ctx).Sugar().Errorf("graphql resolver panic %v", err)
+		return gqlError(ctx, "internal server error", codeInternalError)
+	})
+
+	return &GraphQLHandler{srv: srv, bundle: bundle}, nil
+}
+
+// ServeHTTP attach request scoped loaders and execute operation
+func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.srv.ServeHTTP(w, r.WithContext(withLoaders(r.Context(), h.bundle.PersonService)))
+}
+
+// loaders batch loaders of a single request
+type loaders struct {
+	persons *batchLoader[int64, *domain.Person]
+	history *batchLoader[int64, []*domain.PersonHistory]
+}
+
+type loadersKey struct{}
+
+// withLoaders attach loaders fetching within ctx
+func withLoaders(ctx context.Context, ps *domain.PersonService) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		persons: newBatchLoader(ctx, ps.ReadMany),
+		history: newBatchLoader(ctx, ps.HistoryMany),
+	})
+}
+
+func loadersFromContext(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// listSize page size requested by first, used to weigh list fields
+func listSize(first *int) int {
+
+	if first == nil || *first <= 0 {
+		return domain.DefaultPageSize
+	} else if *first > domain.MaxPageSize {
+		return domain.MaxPageSize
+	}
+
+	return *first
+}
+
+// scopeDirective implement @scope, rejecting fields the caller was not granted
+func scopeDirective(policy *auth.Policy) func(ctx context.Context, obj interface{}, next graphql.Resolver, requires string) (interface{}, error) {
+	return func(ctx context.Context, _ interface{}, next graphql.Resolver, requires string) (interface{}, error) {
+
+		principal, _ := domain.PrincipalFromContext(ctx)
+		if !policy.Allows(principal, requires) {
+			return nil, gqlError(ctx, "missing required scope "+requires, codeForbidden)
+		}
+
+		return next(ctx)
+	}
+}
+
+// mutationRateLimit count each root mutation field against the write rate limit, so
+// aliased mutations in one operation are charged separately. The route applies the
+// read limit to every request.
+func mutationRateLimit(limiter *ratelimit.Limiter) graphql.RootFieldMiddleware {
+	return func(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+
+		rc := graphql.GetOperationContext(ctx)
+		if rc.Operation == nil || rc.Operation.Operation != ast.Mutation {
+			return next(ctx)
+		}
+
+		principal, ok := domain.PrincipalFromContext(ctx)
+		if !ok {
+			return next(ctx)
+		}
+
+		result, limited := takeToken(ctx, limiter, RateLimitWrite, principalKey(principal))
+		if limited && !result.Allowed {
+			graphql.AddError(ctx, &gqlerror.Error{
+				Path:    ast.Path{ast.PathName(graphql.GetRootFieldContext(ctx).Field.Alias)},
+				Message: "rate limit exceeded",
+				Extensions: map[string]interface{}{
+					"code":       codeRateLimited,
+					"retryAfter": ceilSeconds(result.RetryAfter),
+				},
+			})
+			return graphql.Null
+		}
+
+		return next(ctx)
+	}
+}
+
+// mutationReadOnly reject mutations while the read only feature flag is enabled
+func mutationReadOnly(flags *features.Flags) graphql.OperationMiddleware {
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+
+		rc := graphql.GetOperationContext(ctx)
+		if rc.Operation == nil || rc.Operation.Operation != ast.Mutation || !flags.Enabled(features.ReadOnly) {
+			return next(ctx)
+		}
+
+		return graphql.OneShot(&graphql.Response{Errors: gqlerror.List{{
+			Message:    errReadOnly.Error(),
+			Extensions: map[string]interface{}{"code": codeUnavailable},
+		}}})
+	}
+}
+
+// depthLimit extension rejecting operations nested deeper than max
+type depthLimit struct {
+	max int
+}
+
+// ExtensionName implement graphql.HandlerExtension
+func (depthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+// Validate implement graphql.HandlerExtension
+func (depthLimit) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext implement graphql.OperationContextMutator
+func (d depthLimit) MutateOperationContext(_ context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+
+	depth := selectionDepth(rc.Operation.SelectionSet)
+	if depth > d.max {
+
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.max)
+		err.Extensions = map[string]interface{}{"code": codeDepthLimit}
+
+		return err
+	}
+
+	return nil
+}
+
+// selectionDepth deepest field nesting of set, fragments count as their fields. Fragment
+// cycles were rejected by validation.
+func selectionDepth(set ast.SelectionSet) int {
+
+	depth := 0
+
+	for _, selection := range set {
+
+		d := 0
+
+		switch s := selection.(type) {
+		case *ast.Field:
+			if len(s.Name) > 1 && s.Name[:2] == "__" {
+				continue
+			}
+			d = 1 + selectionDepth(s.SelectionSet)
+		case *ast.InlineFragment:
+			d = selectionDepth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				d = selectionDepth(s.Definition.SelectionSet)
+			}
+		}
+
+		if d > depth {
+			depth = d
+		}
+	}
+
+	return depth
+}
+
+// gqlError error on the path of the resolved field carrying code
+func gqlError(ctx context.Context, msg, code string) error {
+	return &gqlerror.Error{
+		Path:       graphql.GetPath(ctx),
+		Message:    msg,
+		Extensions: map[string]interface{}{"code": code},
+	}
+}
+
+// graphError map domain errors onto graphql errors, unexpected errors are logged and
+// reported without details
+func graphError(ctx context.Context, msg string, err error) error {
+
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return gqlError(ctx, "person id does not exist", codeNotFound)
+	case errors.Is(err, domain.ErrForbidden):
+		return gqlError(ctx, "only the owner may modify this person", codeForbidden)
+	case errors.Is(err, domain.ErrNoTenant):
+		return gqlError(ctx, "tenant not provided", codeBadUserInput)
+	}
+
+	logging.FromContext(ctx).Sugar().Errorf("%s %v", msg, err)
+
+	return gqlError(ctx, msg, codeInternalError)
+}
+
+// graphResolver implement graph.ResolverRoot over the person service
+type graphResolver struct {
+	bundle *domain.Bundle
+}
+
+// Query implement graph.ResolverRoot
+func (g *graphResolver) Query() graph.QueryResolver {
+	return &queryResolver{g}
+}
+
+// Mutation implement graph.ResolverRoot
+func (g *graphResolver) Mutation() graph.MutationResolver {
+	return &mutationResolver{g}
+}
+
+// Person implement graph.ResolverRoot
+func (g *graphResolver) Person() graph.PersonResolver {
+	return &personResolver{g}
+}
+
+// PersonPage implement graph.ResolverRoot
+func (g *graphResolver) PersonPage() graph.PersonPageResolver {
+	return &personPageResolver{g}
+}
+
+type queryResolver struct{ *graphResolver }
+
+// Person implement graph.QueryResolver, unknown ids resolve to null
+func (q *queryResolver) Person(ctx context.Context, id int64) (*domain.Person, error) {
+
+	person, ok, err := loadersFromContext(ctx).persons.Load(ctx, id)
+	if err != nil {
+		return nil, graphError(ctx, "unable to read person", err)
+	} else if !ok {
+		return nil, nil
+	}
+
+	return person, nil
+}
+
+// Persons implement graph.QueryResolver
+func (q *queryResolver) Persons(ctx context.Context, first *int, after *int64) (*domain.PersonPage, error) {
+
+	limit, cursor, err := pageArgs(ctx, first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := q.bundle.PersonService.List(ctx, cursor, limit)
+	if err != nil {
+		return nil, graphError(ctx, "unable to list persons", err)
+	}
+
+	return page, nil
+}
+
+// SearchPersons implement graph.QueryResolver
+func (q *queryResolver) SearchPersons(ctx context.Context, query string, first *int, after *int64) (*domain.PersonPage, error) {
+
+	if query == "" {
+		return nil, gqlError(ctx, "no search query provided", codeBadUserInput)
+	}
+
+	limit, cursor, err := pageArgs(ctx, first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := q.bundle.PersonService.Search(ctx, query, cursor, limit)
+	if err != nil {
+		return nil, graphError(ctx, "unable to search persons", err)
+	}
+
+	return page, nil
+}
+
+// pageArgs validate paging arguments
+func pageArgs(ctx context.Context, first *int, after *int64) (int, int64, error) {
+
+	limit, cursor := 0, int64(0)
+
+	if first != nil {
+		if *first < 0 {
+			return 0, 0, gqlError(ctx, "first must not be negative", codeBadUserInput)
+		}
+		limit = *first
+	}
+
+	if after != nil {
+		if *after < 0 {
+			return 0, 0, gqlError(ctx, "invalid cursor", codeBadUserInput)
+		}
+		cursor = *after
+	}
+
+	return limit, cursor, nil
+}
+
+type mutationResolver struct{ *graphResolver }
+
+// CreatePerson implement graph.MutationResolver
+func (m *mutationResolver) CreatePerson(ctx context.Context, input domain.NewPerson) (*domain.Person, error) {
+
+	// same validation as the http port
+	if err := (&domain.NewPersonRequest{NewPerson: &input}).Bind(nil); err != nil {
+		return nil, gqlError(ctx, err.Error(), codeBadUserInput)
+	}
+
+	person, err := m.bundle.PersonService.Create(ctx, &input)
+	if err != nil {
+		return nil, graphError(ctx, "unable to create new person", err)
+	}
+
+	return person, nil
+}
+
+// UpdatePerson implement graph.MutationResolver
+func (m *mutationResolver) UpdatePerson(ctx context.Context, input domain.UpdatePerson) (*domain.Person, error) {
+
+	person, err := m.bundle.PersonService.Update(ctx, &input)
+	if err != nil {
+		return nil, graphError(ctx, "unable to update person", err)
+	}
+
+	return person, nil
+}
+
+// TransferPerson implement graph.MutationResolver
+func (m *mutationResolver) TransferPerson(ctx context.Context, id int64, owner string) (*domain.Person, error) {
+
+	transfer := &domain.TransferPerson{Owner: owner}
+	if err := (&domain.TransferPersonRequest{TransferPerson: transfer}).Bind(nil); err != nil {
+		return nil, gqlError(ctx, err.Error(), codeBadUserInput)
+	}
+
+	person, err := m.bundle.PersonService.TransferOwnership(ctx, id, transfer)
+	if err != nil {
+		return nil, graphError(ctx, "unable to transfer person", err)
+	}
+
+	return person, nil
+}
+
+// DeletePerson implement graph.MutationResolver
+func (m *mutationResolver) DeletePerson(ctx context.Context, id int64) (bool, error) {
+
+	if err := m.bundle.PersonService.Delete(ctx, id); err != nil {
+		return false, graphError(ctx, "unable to delete person", err)
+	}
+
+	return true, nil
+}
+
+// ErasePerson implement graph.MutationResolver
+func (m *mutationResolver) ErasePerson(ctx context.Context, id int64) (bool, error) {
+
+	if err := m.bundle.PersonService.Erase(ctx, id); err != nil {
+		return false, graphError(ctx, "unable to erase person", err)
+	}
+
+	logging.FromContext(ctx).Sugar().Infof("data subject erasure request fulfilled for person %d", id)
+
+	return true, nil
+}
+
+type personResolver struct{ *graphResolver }
+
+// UpdatedAt implement graph.PersonResolver, null when never updated
+func (p *personResolver) UpdatedAt(_ context.Context, obj *domain.Person) (*time.Time, error) {
+
+	if obj.UpdatedAt.IsZero() {
+		return nil, nil
+	}
+
+	return &obj.UpdatedAt, nil
+}
+
+// History implement graph.PersonResolver, audit trails of all persons of a page are
+// fetched by a single query
+func (p *personResolver) History(ctx context.Context, obj *domain.Person) ([]*domain.PersonHistory, error) {
+
+	history, _, err := loadersFromContext(ctx).history.Load(ctx, obj.ID)
+	if err != nil {
+		return nil, graphError(ctx, "unable to read person history", err)
+	}
+
+	if history == nil {
+		history = []*domain.PersonHistory{}
+	}
+
+	return history, nil
+}
+
+type personPageResolver struct{ *graphResolver }
+
+// NextAfter implement graph.PersonPageResolver, null on the last page
+func (p *personPageResolver) NextAfter(_ context.Context, obj *domain.PersonPage) (*int64, error) {
+
+	if obj.NextAfter == 0 {
+		return nil, nil
+	}
+
+	return &obj.NextAfter, nil
+}
@@ -0,0 +1,118 @@
+package port
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// loaderWait time keys are collected before a batch is fetched
+	loaderWait = time.Millisecond
+	// loaderMaxBatch keys fetched at most by a single batch
+	loaderMaxBatch = 100
+)
+
+// batchLoader collect keys requested by concurrently resolved fields and fetch them with a
+// single call, results are cached for the lifetime of the loader which is one request
+type batchLoader[K comparable, V any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu    sync.Mutex
+	cache map[K]V
+	batch *loaderBatch[K, V]
+}
+
+// loaderBatch keys collected for a single fetch
+type loaderBatch[K comparable, V any] struct {
+	keys    []K
+	seen    map[K]struct{}
+	full    chan struct{}
+	done    chan struct{}
+	results map[K]V
+	err     error
+}
+
+// newBatchLoader create new batch loader fetching within ctx
+func newBatchLoader[K comparable, V any](ctx context.Context, fetch func(ctx context.Context, keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{ctx: ctx, fetch: fetch, cache: map[K]V{}}
+}
+
+// Load value of key, ok is false when the batch did not return a value for key
+func (l *batchLoader[K, V]) Load(ctx context.Context, key K) (value V, ok bool, err error) {
+
+	l.mu.Lock()
+
+	if v, ok := l.cache[key]; ok {
+		l.mu.Unlock()
+		return v, true, nil
+	}
+
+	b := l.batch
+	if b == nil {
+		b = &loaderBatch[K, V]{
+			seen: map[K]struct{}{},
+			full: make(chan struct{}),
+			done: make(chan struct{}),
+		}
+		l.batch = b
+		go l.dispatch(b)
+	}
+
+	if _, ok := b.seen[key]; !ok {
+		b.seen[key] = struct{}{}
+		b.keys = append(b.keys, key)
+
+		// later keys start a new batch
+		if len(b.keys) == loaderMaxBatch {
+			l.batch = nil
+			close(b.full)
+		}
+	}
+
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return value, false, ctx.Err()
+	}
+
+	if b.err != nil {
+		return value, false, b.err
+	}
+
+	value, ok = b.results[key]
+
+	return value, ok, nil
+}
+
+// dispatch fetch batch once full or the wait elapsed
+func (l *batchLoader[K, V]) dispatch(b *loaderBatch[K, V]) {
+
+	timer := time.NewTimer(loaderWait)
+	defer timer.Stop()
+
+	select {
+	case <-b.full:
+	case <-timer.C:
+		l.mu.Lock()
+		if l.batch == b {
+			l.batch = nil
+		}
+		l.mu.Unlock()
+	}
+
+	b.results, b.err = l.fetch(l.ctx, b.keys)
+
+	if b.err == nil {
+		l.mu.Lock()
+		for k, v := range b.results {
+			l.cache[k] = v
+		}
+		l.mu.Unlock()
+	}
+
+	close(b.done)
+}
@@ -0,0 +1,231 @@
+package port
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/trevatk/go-template/internal/auth"
+	"github.com/trevatk/go-template/internal/ratelimit"
+)
+
+// graphQLResponse decoded graphql response
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// graphQL execute query with variables authenticated by token, an empty token selects
+// the default test token
+func (suite *HTTPServerSuite) graphQL(token, query string, variables map[string]interface{}) *graphQLResponse {
+
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rr := suite.do(req)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	resp := &graphQLResponse{}
+	assert.NoError(suite.T(), json.NewDecoder(rr.Body).Decode(resp))
+
+	return resp
+}
+
+// code error code of the first error, empty without errors
+func (r *graphQLResponse) code() string {
+
+	if len(r.Errors) == 0 {
+		return ""
+	}
+
+	code, _ := r.Errors[0].Extensions["code"].(string)
+
+	return code
+}
+
+func (suite *HTTPServerSuite) TestGraphQL() {
+
+	assert := assert.New(suite.T())
+
+	// mutations mirror the person service
+	resp := suite.graphQL("", `mutation($input: NewPerson!) {
+		createPerson(input: $input) { id owner firstName updatedAt }
+	}`, map[string]interface{}{"input": map[string]string{
+		"firstName": "graph",
+		"lastName":  "ql",
+		"email":     "graph.ql@mailbox.com",
+	}})
+	assert.Empty(resp.Errors)
+
+	var created struct {
+		ID        string  `json:"id"`
+		Owner     string  `json:"owner"`
+		FirstName string  `json:"firstName"`
+		UpdatedAt *string `json:"updatedAt"`
+	}
+	assert.NoError(json.Unmarshal(resp.Data["createPerson"], &created))
+	assert.Equal("unit-test", created.Owner)
+	assert.Nil(created.UpdatedAt)
+
+	resp = suite.graphQL("", `mutation($input: NewPerson!) { createPerson(input: $input) { id } }`,
+		map[string]interface{}{"input": map[string]string{"firstName": "graph", "lastName": "", "email": "x@mailbox.com"}})
+	assert.Equal(codeBadUserInput, resp.code())
+
+	resp = suite.graphQL("", `mutation($input: UpdatePerson!) { updatePerson(input: $input) { firstName updatedAt } }`,
+		map[string]interface{}{"input": map[string]string{"id": created.ID, "firstName": "graphed", "lastName": "ql", "email": "graph.ql@mailbox.com"}})
+	assert.Empty(resp.Errors)
+	assert.Contains(string(resp.Data["updatePerson"]), `"firstName":"graphed"`)
+	assert.NotContains(string(resp.Data["updatePerson"]), `"updatedAt":null`)
+
+	// queries, unknown ids resolve to null
+	resp = suite.graphQL("", fmt.Sprintf(`{
+		read: person(id: %d) { firstName }
+		missing: person(id: 999999) { firstName }
+	}`, readUserID), nil)
+	assert.Empty(resp.Errors)
+	assert.JSONEq(`{"firstName":"read"}`, string(resp.Data["read"]))
+	assert.Equal("null", string(resp.Data["missing"]))
+
+	resp = suite.graphQL("", `{ searchPersons(query: "graphed") { persons { id } nextAfter } }`, nil)
+	assert.Empty(resp.Errors)
+	assert.JSONEq(fmt.Sprintf(`{"persons":[{"id":%q}],"nextAfter":null}`, created.ID), string(resp.Data["searchPersons"]))
+
+	// like wildcards are matched literally
+	resp = suite.graphQL("", `{ searchPersons(query: "%") { persons { id } } }`, nil)
+	assert.Empty(resp.Errors)
+	assert.JSONEq(`{"persons":[]}`, string(resp.Data["searchPersons"]))
+
+	resp = suite.graphQL("", `{ persons(first: 1) { persons { id } nextAfter } }`, nil)
+	assert.Empty(resp.Errors)
+	assert.NotContains(string(resp.Data["persons"]), `"nextAfter":null`)
+
+	resp = suite.graphQL("", `{ persons(first: -1) { nextAfter } }`, nil)
+	assert.Equal(codeBadUserInput, resp.code())
+
+	// audit trails of a page are read by a single batch
+	ended := len(suite.spans.Ended())
+
+	resp = suite.graphQL("", `{ persons(first: 20) { persons { id history { action actor } } } }`, nil)
+	assert.Empty(resp.Errors)
+	assert.Contains(string(resp.Data["persons"]), `"history":[{"action":"created","actor":"unit-test"}`)
+
+	batches := 0
+	for _, s := range suite.spans.Ended()[ended:] {
+		assert.NotEqual("ListPersonHistory", s.Name())
+		if s.Name() == "PersonService.HistoryMany" {
+			batches++
+		}
+	}
+	assert.Equal(1, batches)
+
+	// scopes are enforced per field
+	readOnly := signToken(jwt.MapClaims{
+		"sub":    "reader",
+		"tenant": testTenant,
+		"scope":  "person:read",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	resp = suite.graphQL(readOnly, fmt.Sprintf(`mutation { deletePerson(id: %d) }`, deleteUserID), nil)
+	assert.Equal(codeForbidden, resp.code())
+
+	resp = suite.graphQL(readOnly, fmt.Sprintf(`{ person(id: %d) { id } }`, readUserID), nil)
+	assert.Empty(resp.Errors)
+
+	// tokens without tenant claim do not select a tenant with the header
+	noTenant := signToken(jwt.MapClaims{
+		"sub":   "reader",
+		"scope": "person:read",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ persons(first: 10) { persons { id } } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+noTenant)
+	req.Header.Set(TenantHeader, otherTenant)
+
+	rr := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rr, req)
+	assert.Equal(http.StatusForbidden, rr.Code)
+
+	resp = suite.graphQL(validToken("other-user"), fmt.Sprintf(`mutation { deletePerson(id: %d) }`, deleteUserID), nil)
+	assert.Equal(codeForbidden, resp.code())
+
+	resp = suite.graphQL("", fmt.Sprintf(`mutation { deletePerson(id: %d) }`, deleteUserID), nil)
+	assert.Empty(resp.Errors)
+
+	resp = suite.graphQL("", fmt.Sprintf(`mutation { deletePerson(id: %d) }`, deleteUserID), nil)
+	assert.Equal(codeNotFound, resp.code())
+
+	// depth and complexity limits, introspection fields do not count towards the depth
+	resp = suite.graphQL("", `{ __schema { types { fields { type { ofType { ofType { ofType { ofType { ofType { ofType { name } } } } } } } } } } }`, nil)
+	assert.Empty(resp.Errors)
+
+	resp = suite.graphQL("", `{ persons(first: 500) { persons { id firstName lastName email history { id } } } }`, nil)
+	assert.Equal("COMPLEXITY_LIMIT_EXCEEDED", resp.code())
+
+	cfg := DefaultGraphQLConfig()
+	cfg.MaxDepth = 2
+
+	shallow, err := NewGraphQLHandler(nil, auth.NewPolicy(), suite.limiter, suite.flags, cfg)
+	assert.NoError(err)
+
+	// rejected before any field is resolved, fragments count as their fields
+	req = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(
+		`{"query":"{ persons { ...page } } fragment page on PersonPage { persons { id } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	shallow.srv.ServeHTTP(rr, req)
+
+	resp = &graphQLResponse{}
+	assert.NoError(json.NewDecoder(rr.Body).Decode(resp))
+	assert.Equal(codeDepthLimit, resp.code())
+	assert.Equal("operation has depth 3, which exceeds the limit of 2", resp.Errors[0].Message)
+}
+
+func (suite *HTTPServerSuite) TestGraphQLMutationRateLimit() {
+
+	assert := assert.New(suite.T())
+
+	suite.limiter.SetLimits(map[string]ratelimit.Limit{
+		RateLimitWrite: {Requests: 2, Period: time.Minute},
+	})
+
+	// aliased mutations are charged one token each
+	resp := suite.graphQL(validToken("graphql-limited"), fmt.Sprintf(`mutation {
+		a: deletePerson(id: %[1]d)
+		b: deletePerson(id: %[1]d)
+		c: deletePerson(id: %[1]d)
+	}`, readUserID+999), nil)
+
+	assert.Len(resp.Errors, 3)
+
+	codes := map[string]int{}
+	for _, e := range resp.Errors {
+		code, _ := e.Extensions["code"].(string)
+		codes[code]++
+	}
+	assert.Equal(map[string]int{codeNotFound: 2, codeRateLimited: 1}, codes)
+
+	// queries are not charged against the write limit
+	resp = suite.graphQL(validToken("graphql-limited"), fmt.Sprintf(`{ person(id: %d) { id } }`, readUserID), nil)
+	assert.Empty(resp.Errors)
+}
@@ -0,0 +1,199 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/trevatk/go-template/internal/auth"
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/features"
+	"github.com/trevatk/go-template/internal/metrics"
+	personv1 "github.com/trevatk/go-template/internal/proto/person/v1"
+	"github.com/trevatk/go-template/internal/ratelimit"
+)
+
+// GRPCConfig grpc server configuration, tls follows the http tls settings
+type GRPCConfig struct {
+	// Port tcp port the grpc server listens on
+	Port int `yaml:"port" env:"GRPC_PORT"`
+	// DrainTimeout time given to in-flight calls to complete on shutdown
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"GRPC_DRAIN_TIMEOUT"`
+}
+
+// DefaultGRPCConfig grpc server defaults
+func DefaultGRPCConfig() *GRPCConfig {
+	return &GRPCConfig{
+		Port:         9091,
+		DrainTimeout: time.Second * 10,
+	}
+}
+
+// Validate check configuration values
+func (c *GRPCConfig) Validate() error {
+
+	var errs []error
+
+	if c.Port < 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid port %d", c.Port))
+	}
+
+	if c.DrainTimeout <= 0 {
+		errs = append(errs, errors.New("drain timeout must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// GRPCServer grpc transport of the person service
+type GRPCServer struct {
+	personv1.UnimplementedPersonServiceServer
+
+	srv          *grpc.Server
+	health       *grpchealth.Server
+	bundle       *domain.Bundle
+	log          *zap.SugaredLogger
+	addr         string
+	drainTimeout time.Duration
+
+	// done closed on shutdown to end long running watch streams
+	done chan struct{}
+}
+
+// NewGRPCServer create new grpc server instance serving the person service, reflection and
+// the grpc health checking protocol
+func NewGRPCServer(
+	logger *zap.Logger,
+	bundle *domain.Bundle,
+	policy *auth.Policy,
+	jwtVerifier *auth.JWTVerifier,
+	apiKeyAuthenticator *auth.APIKeyAuthenticator,
+	certificateAuthenticator *auth.CertificateAuthenticator,
+	limiter *ratelimit.Limiter,
+	flags *features.Flags,
+	grpcMetrics *metrics.GRPCMetrics,
+	cfg *GRPCConfig,
+	serverCfg *ServerConfig,
+) (*GRPCServer, error) {
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	log := logger.Named("grpc server")
+
+	authenticator := &grpcAuthenticator{
+		policy:         policy,
+		authenticators: []auth.Authenticator{jwtVerifier, apiKeyAuthenticator, certificateAuthenticator},
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			unaryRecovery(log),
+			unaryLogging(log),
+			unaryMetrics(grpcMetrics),
+			authenticator.unary,
+			unaryRateLimit(limiter),
+			unaryReadOnly(flags),
+		),
+		grpc.ChainStreamInterceptor(
+			streamRecovery(log),
+			streamLogging(log),
+			streamMetrics(grpcMetrics),
+			authenticator.stream,
+			streamRateLimit(limiter),
+		),
+	}
+
+	tlsConfig, err := NewTLSConfig(logger, &serverCfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	s := &GRPCServer{
+		srv:          grpc.NewServer(opts...),
+		health:       grpchealth.NewServer(),
+		bundle:       bundle,
+		log:          log.Sugar(),
+		addr:         ":" + strconv.Itoa(cfg.Port),
+		drainTimeout: cfg.DrainTimeout,
+		done:         make(chan struct{}),
+	}
+
+	personv1.RegisterPersonServiceServer(s.srv, s)
+	healthpb.RegisterHealthServer(s.srv, s.health)
+	reflection.Register(s.srv)
+
+	// not serving until started
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	s.health.SetServingStatus(personv1.PersonService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	return s, nil
+}
+
+// Listen bind configured address
+func (s *GRPCServer) Listen() (net.Listener, error) {
+
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s %v", s.addr, err)
+	}
+
+	return l, nil
+}
+
+// Serve report serving and accept connections on l until shutdown
+func (s *GRPCServer) Serve(l net.Listener) error {
+
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus(personv1.PersonService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	err := s.srv.Serve(l)
+	if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown report not serving, end watch streams and drain in-flight calls. Calls still
+// running after the drain timeout are cancelled.
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+
+	s.health.Shutdown()
+	close(s.done)
+
+	drained := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(drained)
+	}()
+
+	timer := time.NewTimer(s.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	s.srv.Stop()
+
+	return errors.New("failed to drain grpc server, remaining calls cancelled")
+}
@@ -0,0 +1,359 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/trevatk/go-template/internal/auth"
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/features"
+	"github.com/trevatk/go-template/internal/logging"
+	"github.com/trevatk/go-template/internal/metrics"
+	personv1 "github.com/trevatk/go-template/internal/proto/person/v1"
+	"github.com/trevatk/go-template/internal/ratelimit"
+)
+
+// grpcScopes scopes required by person service methods, methods of other services such as
+// health and reflection are not authenticated
+var grpcScopes = map[string]string{
+	personv1.PersonService_CreatePerson_FullMethodName: auth.ScopePersonWrite,
+	personv1.PersonService_GetPerson_FullMethodName:    auth.ScopePersonRead,
+	personv1.PersonService_UpdatePerson_FullMethodName: auth.ScopePersonWrite,
+	personv1.PersonService_DeletePerson_FullMethodName: auth.ScopePersonDelete,
+	personv1.PersonService_ListPersons_FullMethodName:  auth.ScopePersonRead,
+	personv1.PersonService_WatchPersons_FullMethodName: auth.ScopePersonRead,
+}
+
+// grpcRateLimits rate limit group of person service methods, matching the route groups of
+// the http port
+var grpcRateLimits = map[string]string{
+	personv1.PersonService_CreatePerson_FullMethodName: RateLimitWrite,
+	personv1.PersonService_GetPerson_FullMethodName:    RateLimitRead,
+	personv1.PersonService_UpdatePerson_FullMethodName: RateLimitWrite,
+	personv1.PersonService_DeletePerson_FullMethodName: RateLimitWrite,
+	personv1.PersonService_ListPersons_FullMethodName:  RateLimitRead,
+	personv1.PersonService_WatchPersons_FullMethodName: RateLimitRead,
+}
+
+// contextStream server stream carrying a derived context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context return derived context
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcRequest adapt call metadata and peer onto a http request so authenticators and tenant
+// resolvers of the http port apply to grpc calls unchanged
+func grpcRequest(ctx context.Context, method string) *http.Request {
+
+	header := http.Header{}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, v := range md {
+		header[http.CanonicalHeaderKey(k)] = v
+	}
+
+	r := &http.Request{Method: http.MethodPost, RequestURI: method, Header: header}
+
+	if p, ok := peer.FromContext(ctx); ok {
+
+		r.RemoteAddr = p.Addr.String()
+
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+
+	return r.WithContext(ctx)
+}
+
+// requestContext assign request id from metadata or a new one and attach request scoped logger
+func requestContext(ctx context.Context, logger *zap.Logger) (context.Context, string) {
+
+	id := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RequestIDHeader); len(v) > 0 {
+			id = v[0]
+		}
+	}
+
+	if !requestIDPattern.MatchString(id) {
+		id = newRequestID()
+	}
+
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	ctx = logging.WithContext(ctx, logger.With(zap.String("request_id", id)))
+	ctx = context.WithValue(ctx, accessEntryKey{}, &accessEntry{})
+
+	return ctx, id
+}
+
+// logCall emit one structured access log line per call
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+
+	principal := ""
+	if entry, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+		principal = entry.principal
+	}
+
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+
+	logging.FromContext(ctx).Named("access").Info("rpc",
+		zap.String("method", method),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("latency", time.Since(start)),
+		zap.String("principal", principal),
+		zap.String("remote_addr", remoteAddr),
+	)
+}
+
+// recoverCall map a panic of the call onto codes.Internal, the panic is logged with its stack
+func recoverCall(logger *zap.Logger, method string, err *error) {
+
+	rec := recover()
+	if rec == nil {
+		return
+	}
+
+	logger.Error("rpc panic",
+		zap.String("method", method),
+		zap.Any("panic", rec),
+		zap.Stack("stack"),
+	)
+
+	*err = status.Error(codes.Internal, "internal server error")
+}
+
+// unaryRecovery interceptor turning panics of unary calls into internal errors
+func unaryRecovery(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+
+		defer recoverCall(logger, info.FullMethod, &err)
+
+		return handler(ctx, req)
+	}
+}
+
+// streamRecovery interceptor turning panics of streams into internal errors
+func streamRecovery(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+
+		defer recoverCall(logger, info.FullMethod, &err)
+
+		return handler(srv, ss)
+	}
+}
+
+// unaryLogging interceptor assigning request ids and logging each call
+func unaryLogging(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		start := time.Now()
+
+		ctx, id := requestContext(ctx, logger)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+
+		resp, err := handler(ctx, req)
+
+		logCall(ctx, info.FullMethod, start, err)
+
+		return resp, err
+	}
+}
+
+// streamLogging interceptor assigning request ids and logging each stream once closed
+func streamLogging(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		start := time.Now()
+
+		ctx, id := requestContext(ss.Context(), logger)
+		_ = ss.SetHeader(metadata.Pairs(RequestIDHeader, id))
+
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+
+		logCall(ctx, info.FullMethod, start, err)
+
+		return err
+	}
+}
+
+// unaryMetrics interceptor observing call count and latency
+func unaryMetrics(m *metrics.GRPCMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		m.Observe(info.FullMethod, status.Code(err).String(), time.Since(start))
+
+		return resp, err
+	}
+}
+
+// streamMetrics interceptor observing stream count and lifetime
+func streamMetrics(m *metrics.GRPCMetrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		start := time.Now()
+
+		err := handler(srv, ss)
+
+		m.Observe(info.FullMethod, status.Code(err).String(), time.Since(start))
+
+		return err
+	}
+}
+
+// limitCall take a token of the method group for the client of the call, streams take a
+// single token when opened
+func limitCall(ctx context.Context, limiter *ratelimit.Limiter, method string) error {
+
+	group, ok := grpcRateLimits[method]
+	if !ok {
+		return nil
+	}
+
+	result, limited := takeToken(ctx, limiter, group, clientKey(grpcRequest(ctx, method)))
+	if limited && !result.Allowed {
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %ss", ceilSeconds(result.RetryAfter))
+	}
+
+	return nil
+}
+
+// unaryRateLimit interceptor limiting unary calls of each principal
+func unaryRateLimit(limiter *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		if err := limitCall(ctx, limiter, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// streamRateLimit interceptor limiting streams opened by each principal
+func streamRateLimit(limiter *ratelimit.Limiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		if err := limitCall(ss.Context(), limiter, info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// unaryReadOnly interceptor rejecting person changes while the read only feature flag is
+// enabled, streams only read
+func unaryReadOnly(flags *features.Flags) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		if scope, ok := grpcScopes[info.FullMethod]; ok && scope != auth.ScopePersonRead && flags.Enabled(features.ReadOnly) {
+			return nil, status.Error(codes.Unavailable, errReadOnly.Error())
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// grpcAuthenticator authenticate, authorize and scope calls to a tenant
+type grpcAuthenticator struct {
+	policy         *auth.Policy
+	authenticators []auth.Authenticator
+}
+
+// authorize return context carrying principal and tenant of the call
+func (a *grpcAuthenticator) authorize(ctx context.Context, method string) (context.Context, error) {
+
+	scope, ok := grpcScopes[method]
+	if !ok {
+		return ctx, nil
+	}
+
+	r := grpcRequest(ctx, method)
+
+	var principal *domain.Principal
+	for _, authenticator := range a.authenticators {
+
+		p, err := authenticator.Authenticate(r)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			continue
+		} else if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		} else if err != nil {
+			logging.FromContext(ctx).Sugar().Errorf("unable to authenticate call %v", err)
+			return nil, status.Error(codes.Internal, "unable to authenticate call")
+		}
+
+		principal = p
+		break
+	}
+
+	if principal == nil {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	recordPrincipal(ctx, principal)
+
+	ctx = domain.WithPrincipal(ctx, principal)
+	ctx = logging.WithContext(ctx, logging.FromContext(ctx).With(zap.String("principal", principal.Subject)))
+
+	if !a.policy.Allows(principal, scope) {
+		return nil, status.Error(codes.PermissionDenied, "missing required scope "+scope)
+	}
+
+	tenantID, err := requestTenant(a.policy, principal, r.Header.Get(TenantHeader))
+	if errors.Is(err, errTenantDenied) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	} else if errors.Is(err, errNoTenant) {
+		return nil, status.Error(codes.InvalidArgument, "tenant not provided, set "+strings.ToLower(TenantHeader)+" metadata")
+	} else if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return domain.WithTenant(ctx, tenantID), nil
+}
+
+// unary interceptor authorizing unary calls
+func (a *grpcAuthenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// stream interceptor authorizing streams
+func (a *grpcAuthenticator) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	ctx, err := a.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
@@ -0,0 +1,199 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/logging"
+	personv1 "github.com/trevatk/go-template/internal/proto/person/v1"
+)
+
+var watchActions = map[string]personv1.WatchPersonsResponse_Action{
+	domain.HistoryActionCreated:     personv1.WatchPersonsResponse_ACTION_CREATED,
+	domain.HistoryActionUpdated:     personv1.WatchPersonsResponse_ACTION_UPDATED,
+	domain.HistoryActionTransferred: personv1.WatchPersonsResponse_ACTION_TRANSFERRED,
+	domain.HistoryActionDeleted:     personv1.WatchPersonsResponse_ACTION_DELETED,
+	domain.HistoryActionErased:      personv1.WatchPersonsResponse_ACTION_ERASED,
+}
+
+// CreatePerson implement personv1.PersonServiceServer
+func (s *GRPCServer) CreatePerson(ctx context.Context, req *personv1.CreatePersonRequest) (*personv1.CreatePersonResponse, error) {
+
+	newPerson := &domain.NewPerson{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Email:     req.GetEmail(),
+	}
+
+	// same validation as the http port
+	if err := (&domain.NewPersonRequest{NewPerson: newPerson}).Bind(nil); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	person, err := s.bundle.PersonService.Create(ctx, newPerson)
+	if err != nil {
+		return nil, grpcError(ctx, "unable to create new person", err)
+	}
+
+	return &personv1.CreatePersonResponse{Person: toProtoPerson(person)}, nil
+}
+
+// GetPerson implement personv1.PersonServiceServer
+func (s *GRPCServer) GetPerson(ctx context.Context, req *personv1.GetPersonRequest) (*personv1.GetPersonResponse, error) {
+
+	person, err := s.bundle.PersonService.Read(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(ctx, "unable to read person", err)
+	}
+
+	return &personv1.GetPersonResponse{Person: toProtoPerson(person)}, nil
+}
+
+// UpdatePerson implement personv1.PersonServiceServer
+func (s *GRPCServer) UpdatePerson(ctx context.Context, req *personv1.UpdatePersonRequest) (*personv1.UpdatePersonResponse, error) {
+
+	person, err := s.bundle.PersonService.Update(ctx, &domain.UpdatePerson{
+		ID:        req.GetId(),
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Email:     req.GetEmail(),
+	})
+	if err != nil {
+		return nil, grpcError(ctx, "unable to update person", err)
+	}
+
+	return &personv1.UpdatePersonResponse{Person: toProtoPerson(person)}, nil
+}
+
+// DeletePerson implement personv1.PersonServiceServer
+func (s *GRPCServer) DeletePerson(ctx context.Context, req *personv1.DeletePersonRequest) (*personv1.DeletePersonResponse, error) {
+
+	if err := s.bundle.PersonService.Delete(ctx, req.GetId()); err != nil {
+		return nil, grpcError(ctx, "unable to delete person", err)
+	}
+
+	return &personv1.DeletePersonResponse{}, nil
+}
+
+// ListPersons implement personv1.PersonServiceServer, page tokens are the id of the last
+// person of the previous page
+func (s *GRPCServer) ListPersons(ctx context.Context, req *personv1.ListPersonsRequest) (*personv1.ListPersonsResponse, error) {
+
+	if req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page size must not be negative")
+	}
+
+	var after int64
+	if token := req.GetPageToken(); token != "" {
+
+		var err error
+		after, err = strconv.ParseInt(token, 10, 64)
+		if err != nil || after < 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+	}
+
+	page, err := s.bundle.PersonService.List(ctx, after, int(req.GetPageSize()))
+	if err != nil {
+		return nil, grpcError(ctx, "unable to list persons", err)
+	}
+
+	resp := &personv1.ListPersonsResponse{Persons: make([]*personv1.Person, 0, len(page.Persons))}
+	for _, person := range page.Persons {
+		resp.Persons = append(resp.Persons, toProtoPerson(person))
+	}
+
+	if page.NextAfter != 0 {
+		resp.NextPageToken = strconv.FormatInt(page.NextAfter, 10)
+	}
+
+	return resp, nil
+}
+
+// WatchPersons implement personv1.PersonServiceServer
+func (s *GRPCServer) WatchPersons(_ *personv1.WatchPersonsRequest, stream personv1.PersonService_WatchPersonsServer) error {
+
+	ctx := stream.Context()
+
+	events, err := s.bundle.PersonService.Watch(ctx)
+	if err != nil {
+		return grpcError(ctx, "unable to watch persons", err)
+	}
+
+	// headers tell the client the watch is established and no change will be missed
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server shutting down")
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind, watch again")
+			}
+
+			err := stream.Send(&personv1.WatchPersonsResponse{
+				Action:   watchActions[event.Action],
+				PersonId: event.PersonID,
+				Person:   toProtoPerson(event.Person),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcError map domain errors onto status codes, unexpected errors are logged and reported
+// as internal without details
+func grpcError(ctx context.Context, msg string, err error) error {
+
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return status.Error(codes.NotFound, "person id does not exist")
+	case errors.Is(err, domain.ErrForbidden):
+		return status.Error(codes.PermissionDenied, "only the owner may modify this person")
+	case errors.Is(err, domain.ErrNoTenant):
+		return status.Error(codes.InvalidArgument, "tenant not provided")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+
+	logging.FromContext(ctx).Sugar().Errorf("%s %v", msg, err)
+
+	return status.Error(codes.Internal, msg)
+}
+
+func toProtoPerson(person *domain.Person) *personv1.Person {
+
+	if person == nil {
+		return nil
+	}
+
+	p := &personv1.Person{
+		Id:        person.ID,
+		TenantId:  person.TenantID,
+		Owner:     person.Owner,
+		FirstName: person.FirstName,
+		LastName:  person.LastName,
+		Email:     person.Email,
+		CreatedAt: timestamppb.New(person.CreatedAt),
+	}
+
+	if !person.UpdatedAt.IsZero() {
+		p.UpdatedAt = timestamppb.New(person.UpdatedAt)
+	}
+
+	return p
+}
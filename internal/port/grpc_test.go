@@ -0,0 +1,216 @@
+package port
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/trevatk/go-template/internal/auth"
+	personv1 "github.com/trevatk/go-template/internal/proto/person/v1"
+	"github.com/trevatk/go-template/internal/ratelimit"
+)
+
+// dialGRPC serve the suite grpc server in memory and connect to it
+func (suite *HTTPServerSuite) dialGRPC() *grpc.ClientConn {
+
+	l := bufconn.Listen(1 << 20)
+	go func() { _ = suite.grpc.Serve(l) }()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(suite.T(), err)
+
+	suite.T().Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+// grpcContext outgoing context authenticated as subject of the test tenant
+func grpcContext(subject string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Bearer "+validToken(subject),
+		"x-tenant-id", testTenant,
+	)
+}
+
+func (suite *HTTPServerSuite) TestGRPC() {
+
+	assert := assert.New(suite.T())
+
+	conn := suite.dialGRPC()
+	client := personv1.NewPersonServiceClient(conn)
+
+	ctx := grpcContext("unit-test")
+
+	// health checking protocol
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: personv1.PersonService_ServiceDesc.ServiceName,
+	})
+	assert.NoError(err)
+	assert.Equal(healthpb.HealthCheckResponse_SERVING, health.Status)
+
+	// authentication and validation
+	_, err = client.GetPerson(context.Background(), &personv1.GetPersonRequest{Id: readUserID})
+	assert.Equal(codes.Unauthenticated, status.Code(err))
+
+	_, err = client.CreatePerson(ctx, &personv1.CreatePersonRequest{LastName: "test", Email: "grpc@mailbox.com"})
+	assert.Equal(codes.InvalidArgument, status.Code(err))
+
+	// tokens without tenant claim do not select a tenant with metadata
+	noTenant := signToken(jwt.MapClaims{
+		"sub":   "unit-test",
+		"scope": auth.ScopePersonRead,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	_, err = client.GetPerson(metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Bearer "+noTenant,
+		"x-tenant-id", otherTenant,
+	), &personv1.GetPersonRequest{Id: readUserID})
+	assert.Equal(codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetPerson(metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Bearer "+noTenant,
+	), &personv1.GetPersonRequest{Id: readUserID})
+	assert.Equal(codes.InvalidArgument, status.Code(err))
+
+	// watch established before changes are made
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	watch, err := client.WatchPersons(watchCtx, &personv1.WatchPersonsRequest{})
+	assert.NoError(err)
+	_, err = watch.Header()
+	assert.NoError(err)
+
+	created, err := client.CreatePerson(ctx, &personv1.CreatePersonRequest{FirstName: "grpc", LastName: "test", Email: "grpc@mailbox.com"})
+	assert.NoError(err)
+	assert.Equal("unit-test", created.Person.Owner)
+
+	event, err := watch.Recv()
+	assert.NoError(err)
+	assert.Equal(personv1.WatchPersonsResponse_ACTION_CREATED, event.Action)
+	assert.Equal(created.Person.Id, event.PersonId)
+
+	got, err := client.GetPerson(ctx, &personv1.GetPersonRequest{Id: created.Person.Id})
+	assert.NoError(err)
+	assert.Equal("grpc@mailbox.com", got.Person.Email)
+	assert.Nil(got.Person.UpdatedAt)
+
+	_, err = client.GetPerson(ctx, &personv1.GetPersonRequest{Id: created.Person.Id + 999})
+	assert.Equal(codes.NotFound, status.Code(err))
+
+	// only the owner may modify
+	_, err = client.UpdatePerson(grpcContext("someone-else"), &personv1.UpdatePersonRequest{Id: created.Person.Id, FirstName: "x", LastName: "y", Email: "z"})
+	assert.Equal(codes.PermissionDenied, status.Code(err))
+
+	updated, err := client.UpdatePerson(ctx, &personv1.UpdatePersonRequest{Id: created.Person.Id, FirstName: "grpc", LastName: "updated", Email: "grpc@mailbox.com"})
+	assert.NoError(err)
+	assert.Equal("updated", updated.Person.LastName)
+
+	event, err = watch.Recv()
+	assert.NoError(err)
+	assert.Equal(personv1.WatchPersonsResponse_ACTION_UPDATED, event.Action)
+
+	// page through all persons one at a time
+	var ids []int64
+	token := ""
+	for {
+		page, err := client.ListPersons(ctx, &personv1.ListPersonsRequest{PageSize: 1, PageToken: token})
+		assert.NoError(err)
+		assert.LessOrEqual(len(page.Persons), 1)
+
+		for _, p := range page.Persons {
+			ids = append(ids, p.Id)
+		}
+
+		if token = page.NextPageToken; token == "" {
+			break
+		}
+	}
+	assert.Contains(ids, readUserID)
+	assert.Contains(ids, created.Person.Id)
+	assert.IsIncreasing(ids)
+
+	_, err = client.ListPersons(ctx, &personv1.ListPersonsRequest{PageToken: "not-a-token"})
+	assert.Equal(codes.InvalidArgument, status.Code(err))
+
+	_, err = client.DeletePerson(ctx, &personv1.DeletePersonRequest{Id: created.Person.Id})
+	assert.NoError(err)
+
+	event, err = watch.Recv()
+	assert.NoError(err)
+	assert.Equal(personv1.WatchPersonsResponse_ACTION_DELETED, event.Action)
+	assert.Equal(created.Person.Id, event.PersonId)
+
+	// shutdown ends watch streams
+	assert.NoError(suite.grpc.Shutdown(context.Background()))
+
+	_, err = watch.Recv()
+	assert.Equal(codes.Unavailable, status.Code(err))
+}
+
+func (suite *HTTPServerSuite) TestGRPCRateLimit() {
+
+	assert := assert.New(suite.T())
+
+	suite.limiter.SetLimits(map[string]ratelimit.Limit{
+		RateLimitRead: {Requests: 1, Period: time.Minute},
+	})
+
+	client := personv1.NewPersonServiceClient(suite.dialGRPC())
+
+	_, err := client.GetPerson(grpcContext("unit-test"), &personv1.GetPersonRequest{Id: readUserID})
+	assert.NoError(err)
+
+	_, err = client.GetPerson(grpcContext("unit-test"), &personv1.GetPersonRequest{Id: readUserID})
+	assert.Equal(codes.ResourceExhausted, status.Code(err))
+
+	// streams count against the read limit
+	watch, err := client.WatchPersons(grpcContext("unit-test"), &personv1.WatchPersonsRequest{})
+	assert.NoError(err)
+	_, err = watch.Recv()
+	assert.Equal(codes.ResourceExhausted, status.Code(err))
+
+	// buckets are kept per principal
+	_, err = client.GetPerson(grpcContext("client-b"), &personv1.GetPersonRequest{Id: readUserID})
+	assert.NoError(err)
+
+	// groups without a limit are not limited
+	_, err = client.CreatePerson(grpcContext("unit-test"), &personv1.CreatePersonRequest{FirstName: "grpc", LastName: "limited", Email: "grpc.limited@mailbox.com"})
+	assert.NoError(err)
+}
+
+func (suite *HTTPServerSuite) TestGRPCRecovery() {
+
+	assert := assert.New(suite.T())
+
+	core, logs := observer.New(zap.ErrorLevel)
+
+	_, err := unaryRecovery(zap.New(core))(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/Unary"},
+		func(context.Context, interface{}) (interface{}, error) { panic("unary failed") })
+	assert.Equal(codes.Internal, status.Code(err))
+
+	err = streamRecovery(zap.New(core))(nil, nil, &grpc.StreamServerInfo{FullMethod: "/test/Stream"},
+		func(interface{}, grpc.ServerStream) error { panic("stream failed") })
+	assert.Equal(codes.Internal, status.Code(err))
+
+	if assert.Equal(2, logs.Len()) {
+		assert.Equal("unary failed", logs.All()[0].ContextMap()["panic"])
+		assert.Equal("/test/Stream", logs.All()[1].ContextMap()["method"])
+	}
+}
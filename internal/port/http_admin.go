@@ -0,0 +1,95 @@
+package port
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/trevatk/go-template/internal/domain"
+)
+
+func (h *HTTPServer) createAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	request := &domain.NewAPIKeyRequest{}
+	err := render.Bind(r, request)
+	if err != nil {
+		h.logger(r).Errorf("failed to bind to request %v", err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	apiKey, err := h.bundle.APIKeyService.Create(r.Context(), request.NewAPIKey)
+	if err != nil {
+
+		if errors.Is(err, domain.ErrForbidden) {
+			writeProblem(w, r, http.StatusForbidden, err.Error())
+			return
+		}
+
+		h.logger(r).Errorf("unable to create new api key %v", err)
+		http.Error(w, "unable to create new api key", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger(r).Infof("issued api key %d (%s)", apiKey.ID, apiKey.Prefix)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(apiKey); err != nil {
+		h.logger(r).Errorf("failed to encode response %v", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *HTTPServer) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+
+	apiKeys, err := h.bundle.APIKeyService.List(r.Context())
+	if err != nil {
+		h.logger(r).Errorf("unable to list api keys %v", err)
+		http.Error(w, "unable to list api keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(apiKeys); err != nil {
+		h.logger(r).Errorf("failed to encode response %v", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *HTTPServer) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	sid := chi.URLParam(r, "id")
+	id, err := parseParamInt64(sid)
+	if err != nil {
+		h.logger(r).Errorf("failed to parse param %v", err)
+		http.Error(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	err = h.bundle.APIKeyService.Revoke(r.Context(), id)
+	if err != nil {
+
+		if errors.Is(err, domain.ErrNotFound) {
+			http.Error(w, "api key does not exist", http.StatusNotFound)
+			return
+		}
+
+		h.logger(r).Errorf("failed to revoke api key %v", err)
+		http.Error(w, "failed to revoke api key", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger(r).Infof("revoked api key %d", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode("SUCCESS"); err != nil {
+		h.logger(r).Errorf("unable to encode response %v", err)
+		http.Error(w, "unable to encode response", http.StatusInternalServerError)
+	}
+}
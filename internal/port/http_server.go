@@ -11,9 +11,15 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"github.com/trevatk/go-template/internal/auth"
 	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/features"
+	"github.com/trevatk/go-template/internal/logging"
+	"github.com/trevatk/go-template/internal/metrics"
+	"github.com/trevatk/go-template/internal/ratelimit"
 )
 
 // HTTPServer exposed endpoints
@@ -28,23 +34,89 @@ func NewHTTPServer(logger *zap.Logger, bundle *domain.Bundle) *HTTPServer {
 }
 
 // NewRouter chi router implementation of http handler
-func NewRouter(httpServer *HTTPServer) *chi.Mux {
+func NewRouter(
+	httpServer *HTTPServer,
+	policy *auth.Policy,
+	limiter *ratelimit.Limiter,
+	jwtVerifier *auth.JWTVerifier,
+	apiKeyAuthenticator *auth.APIKeyAuthenticator,
+	certificateAuthenticator *auth.CertificateAuthenticator,
+	httpMetrics *metrics.HTTPMetrics,
+	tracerProvider trace.TracerProvider,
+	cors *CORS,
+	graphQL *GraphQLHandler,
+	openAPI *OpenAPI,
+	flags *features.Flags,
+) *chi.Mux {
 
 	r := chi.NewRouter()
 
+	r.Use(requestID(httpServer.log.Desugar()))
+	r.Use(traceRequest(tracerProvider))
+	r.Use(accessLog)
+	r.Use(instrument(httpMetrics))
+	r.Use(cors.Handler)
 	r.Use(render.SetContentType(render.ContentTypeJSON))
 
 	r.Route("/api/v1", func(r chi.Router) {
 
+		// throttle failed authentication attempts as well
+		r.Use(rateLimit(limiter, RateLimitIP))
+		r.Use(authenticate(jwtVerifier, apiKeyAuthenticator, certificateAuthenticator))
+		r.Use(resolveTenant(policy))
+		r.Use(openAPI.validate)
+
 		r.Route("/person", func(r chi.Router) {
-			r.Post("/", httpServer.createPerson)
-			r.Get("/{id}", httpServer.fetchPerson)
-			r.Put("/", httpServer.updatePerson)
-			r.Delete("/{id}", httpServer.deletePerson)
+
+			r.Group(func(r chi.Router) {
+				r.Use(rateLimit(limiter, RateLimitRead))
+				r.With(authorize(policy, auth.ScopePersonRead)).Get("/", httpServer.listPersons)
+				r.With(authorize(policy, auth.ScopePersonRead)).Get("/{id}", httpServer.fetchPerson)
+				r.With(authorize(policy, auth.ScopePersonRead)).Get("/{id}/export", httpServer.exportPerson)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(rateLimit(limiter, RateLimitWrite))
+				// rejected before idempotent so retries succeed once writes are enabled again
+				r.Use(readOnly(flags))
+				r.Use(idempotent(httpServer.bundle.IdempotencyService))
+				r.With(authorize(policy, auth.ScopePersonWrite)).Post("/", httpServer.createPerson)
+				r.With(authorize(policy, auth.ScopePersonWrite)).Put("/", httpServer.updatePerson)
+				r.With(authorize(policy, auth.ScopePersonDelete)).Delete("/{id}", httpServer.deletePerson)
+				r.With(authorize(policy, auth.ScopePersonDelete)).Post("/{id}/erase", httpServer.erasePerson)
+				r.With(authorize(policy, auth.ScopePersonWrite)).Post("/{id}/transfer", httpServer.transferPerson)
+			})
+		})
+
+		// not idempotent, issued api keys are returned in plaintext and must never be stored
+		r.Route("/admin", func(r chi.Router) {
+
+			r.Use(rateLimit(limiter, RateLimitAdmin))
+
+			r.Route("/apikeys", func(r chi.Router) {
+				r.Use(authorize(policy, auth.ScopeAPIKeyAdmin))
+				r.Post("/", httpServer.createAPIKey)
+				r.Get("/", httpServer.listAPIKeys)
+				r.Delete("/{id}", httpServer.revokeAPIKey)
+			})
 		})
 	})
 
+	// scopes are enforced per field by the @scope directive of the schema
+	r.Route("/graphql", func(r chi.Router) {
+		r.Use(rateLimit(limiter, RateLimitIP))
+		r.Use(authenticate(jwtVerifier, apiKeyAuthenticator, certificateAuthenticator))
+		r.Use(resolveTenant(policy))
+		r.Use(openAPI.validate)
+		r.Use(rateLimit(limiter, RateLimitRead))
+		r.Method(http.MethodGet, "/", graphQL)
+		r.Method(http.MethodPost, "/", graphQL)
+	})
+
 	r.Get("/health", httpServer.health)
+	r.Get("/openapi.json", openAPI.serveDocument)
+	r.Get("/docs", openAPI.serveDocs)
+	r.Get("/docs/{asset}", openAPI.serveDocsAsset)
 
 	return r
 }
@@ -54,21 +126,22 @@ func (h *HTTPServer) createPerson(w http.ResponseWriter, r *http.Request) {
 	request := &domain.NewPersonRequest{}
 	err := render.Bind(r, request)
 	if err != nil {
-		h.log.Errorf("failed to bind to request %v", err)
+		h.logger(r).Errorf("failed to bind to request %v", err)
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
 
 	person, err := h.bundle.PersonService.Create(r.Context(), request.NewPerson)
 	if err != nil {
-		h.log.Errorf("unable to create new person %v", err)
+		h.logger(r).Errorf("unable to create new person %v", err)
 		http.Error(w, "unable to create new person", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(person); err != nil {
-		h.log.Errorf("failed to encode response %v", err)
+		h.logger(r).Errorf("failed to encode response %v", err)
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 	}
 }
@@ -78,7 +151,7 @@ func (h *HTTPServer) fetchPerson(w http.ResponseWriter, r *http.Request) {
 	sid := chi.URLParam(r, "id")
 	id, err := parseParamInt64(sid)
 	if err != nil {
-		h.log.Errorf("failed to parse param into integer %v", err)
+		h.logger(r).Errorf("failed to parse param into integer %v", err)
 
 		w.WriteHeader(http.StatusBadRequest)
 		http.Error(w, "invalid url parameter", http.StatusBadRequest)
@@ -94,14 +167,55 @@ func (h *HTTPServer) fetchPerson(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		h.log.Errorf("unable to read person %v", err)
+		h.logger(r).Errorf("unable to read person %v", err)
 		http.Error(w, "failed to read person", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusAccepted)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(person); err != nil {
-		h.log.Errorf("failed to encode response %v", err)
+		h.logger(r).Errorf("failed to encode response %v", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *HTTPServer) listPersons(w http.ResponseWriter, r *http.Request) {
+
+	var after int64
+	var limit int
+
+	if s := r.URL.Query().Get("after"); s != "" {
+		value, err := parseParamInt64(s)
+		if err != nil || value < 0 {
+			h.logger(r).Errorf("invalid after parameter %q", s)
+			http.Error(w, "invalid query parameter", http.StatusBadRequest)
+			return
+		}
+		after = value
+	}
+
+	if s := r.URL.Query().Get("limit"); s != "" {
+		value, err := strconv.Atoi(s)
+		if err != nil || value <= 0 {
+			h.logger(r).Errorf("invalid limit parameter %q", s)
+			http.Error(w, "invalid query parameter", http.StatusBadRequest)
+			return
+		}
+		limit = value
+	}
+
+	page, err := h.bundle.PersonService.List(r.Context(), after, limit)
+	if err != nil {
+		h.logger(r).Errorf("unable to list persons %v", err)
+		http.Error(w, "failed to list persons", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		h.logger(r).Errorf("failed to encode response %v", err)
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 	}
 }
@@ -111,7 +225,7 @@ func (h *HTTPServer) updatePerson(w http.ResponseWriter, r *http.Request) {
 	request := &domain.UpdatePersonRequest{}
 	err := render.Bind(r, request)
 	if err != nil {
-		h.log.Errorf("failed to bind request to model %v", err)
+		h.logger(r).Errorf("failed to bind request to model %v", err)
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -124,14 +238,20 @@ func (h *HTTPServer) updatePerson(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		h.log.Errorf("failed to update user %v", err)
+		if errors.Is(err, domain.ErrForbidden) {
+			writeProblem(w, r, http.StatusForbidden, "only the owner may modify this person")
+			return
+		}
+
+		h.logger(r).Errorf("failed to update user %v", err)
 		http.Error(w, "failed to update user", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
 	if err := json.NewEncoder(w).Encode(person); err != nil {
-		h.log.Errorf("failed to encode response %v", err)
+		h.logger(r).Errorf("failed to encode response %v", err)
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 	}
 }
@@ -141,7 +261,7 @@ func (h *HTTPServer) deletePerson(w http.ResponseWriter, r *http.Request) {
 	sid := chi.URLParam(r, "id")
 	id, err := parseParamInt64(sid)
 	if err != nil {
-		h.log.Errorf("failed to parse param %v", err)
+		h.logger(r).Errorf("failed to parse param %v", err)
 		http.Error(w, "invalid request parameter", http.StatusBadRequest)
 		return
 	}
@@ -154,28 +274,155 @@ func (h *HTTPServer) deletePerson(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		h.log.Errorf("failed to delete person %v", err)
+		if errors.Is(err, domain.ErrForbidden) {
+			writeProblem(w, r, http.StatusForbidden, "only the owner may modify this person")
+			return
+		}
+
+		h.logger(r).Errorf("failed to delete person %v", err)
 		http.Error(w, "failed to delete person", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
 	if err := json.NewEncoder(w).Encode("SUCCESS"); err != nil {
-		h.log.Errorf("unable to encode response %v", err)
+		h.logger(r).Errorf("unable to encode response %v", err)
 		http.Error(w, "unable to encode response", http.StatusInternalServerError)
 	}
 }
 
-func (h *HTTPServer) health(w http.ResponseWriter, _ *http.Request) {
+func (h *HTTPServer) exportPerson(w http.ResponseWriter, r *http.Request) {
+
+	sid := chi.URLParam(r, "id")
+	id, err := parseParamInt64(sid)
+	if err != nil {
+		h.logger(r).Errorf("failed to parse param %v", err)
+		http.Error(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.bundle.PersonService.Export(r.Context(), id)
+	if err != nil {
+
+		if errors.Is(err, domain.ErrNotFound) {
+			http.Error(w, "person does not exist", http.StatusNotFound)
+			return
+		}
+
+		h.logger(r).Errorf("failed to export person %v", err)
+		http.Error(w, "failed to export person", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger(r).Infof("data subject access request fulfilled for person %d", id)
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"person-%d-export.json\"", id))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(export); err != nil {
+		h.logger(r).Errorf("failed to encode response %v", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *HTTPServer) erasePerson(w http.ResponseWriter, r *http.Request) {
+
+	sid := chi.URLParam(r, "id")
+	id, err := parseParamInt64(sid)
+	if err != nil {
+		h.logger(r).Errorf("failed to parse param %v", err)
+		http.Error(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	err = h.bundle.PersonService.Erase(r.Context(), id)
+	if err != nil {
+
+		if errors.Is(err, domain.ErrNotFound) {
+			http.Error(w, "person does not exist", http.StatusNotFound)
+			return
+		}
+
+		if errors.Is(err, domain.ErrForbidden) {
+			writeProblem(w, r, http.StatusForbidden, "only the owner may modify this person")
+			return
+		}
+
+		h.logger(r).Errorf("failed to erase person %v", err)
+		http.Error(w, "failed to erase person", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger(r).Infof("data subject erasure request fulfilled for person %d", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode("SUCCESS"); err != nil {
+		h.logger(r).Errorf("unable to encode response %v", err)
+		http.Error(w, "unable to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *HTTPServer) transferPerson(w http.ResponseWriter, r *http.Request) {
+
+	sid := chi.URLParam(r, "id")
+	id, err := parseParamInt64(sid)
+	if err != nil {
+		h.logger(r).Errorf("failed to parse param %v", err)
+		http.Error(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	request := &domain.TransferPersonRequest{}
+	err = render.Bind(r, request)
+	if err != nil {
+		h.logger(r).Errorf("failed to bind request to model %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	person, err := h.bundle.PersonService.TransferOwnership(r.Context(), id, request.TransferPerson)
+	if err != nil {
+
+		if errors.Is(err, domain.ErrNotFound) {
+			http.Error(w, "person does not exist", http.StatusNotFound)
+			return
+		}
+
+		if errors.Is(err, domain.ErrForbidden) {
+			writeProblem(w, r, http.StatusForbidden, "only the owner may modify this person")
+			return
+		}
+
+		h.logger(r).Errorf("failed to transfer person %v", err)
+		http.Error(w, "failed to transfer person", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(person); err != nil {
+		h.logger(r).Errorf("failed to encode response %v", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *HTTPServer) health(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 	_, err := w.Write([]byte("OK"))
 	if err != nil {
-		h.log.Errorf("error encoding health check response %v", err)
+		h.logger(r).Errorf("error encoding health check response %v", err)
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 	}
 }
 
+// logger request scoped logger of the http server
+func (h *HTTPServer) logger(r *http.Request) *zap.SugaredLogger {
+	return logging.FromContext(r.Context()).Sugar()
+}
+
 func parseParamInt64(input string) (int64, error) {
 
 	value, err := strconv.ParseInt(input, 10, 64)
@@ -3,21 +3,49 @@ package port
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
-
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/trevatk/go-template/internal/auth"
 	"github.com/trevatk/go-template/internal/db"
 	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/features"
+	"github.com/trevatk/go-template/internal/health"
 	"github.com/trevatk/go-template/internal/logging"
+	"github.com/trevatk/go-template/internal/metrics"
+	personv1 "github.com/trevatk/go-template/internal/proto/person/v1"
+	"github.com/trevatk/go-template/internal/ratelimit"
+	"github.com/trevatk/go-template/internal/tracing"
+)
+
+const (
+	testTenant  = "tenant-a"
+	otherTenant = "tenant-b"
+
+	// signing secret of testfiles/jwks.json
+	testSigningKey = "go-template-test-signing-secret-0123456789"
 )
 
 var (
@@ -25,29 +53,84 @@ var (
 	deleteUserID int64
 )
 
-func init() {
-	_ = os.Setenv("SQLITE_DSN", "./testfiles/sqlite/person.db")
-	_ = os.Setenv("SQLITE_MIGRATIONS_DIR", "./../../migrations")
+var sqliteConfig = &db.Config{
+	DSN:           "./testfiles/sqlite/person.db",
+	MigrationsDir: "./../../migrations",
+}
+
+// signToken issue test token signed with the test key
+func signToken(claims jwt.MapClaims, key string) string {
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = "test"
+
+	signed, err := token.SignedString([]byte(key))
+	if err != nil {
+		panic(err)
+	}
+
+	return signed
+}
+
+// validToken issue unexpired token for subject of the test tenant granted all person scopes
+func validToken(subject string) string {
+	return tenantToken(subject, testTenant)
+}
+
+// tenantToken issue unexpired token for subject of tenantID granted all person scopes
+func tenantToken(subject, tenantID string) string {
+	return signToken(jwt.MapClaims{
+		"sub":    subject,
+		"tenant": tenantID,
+		"scope":  "person:read person:write person:delete",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
 }
 
 type HTTPServerSuite struct {
 	suite.Suite
-	mux *chi.Mux
+	mux     *chi.Mux
+	admin   *chi.Mux
+	grpc    *GRPCServer
+	limiter *ratelimit.Limiter
+	logs    *observer.ObservedLogs
+	level   zap.AtomicLevel
+	spans   *tracetest.SpanRecorder
+	health  *health.Registry
+	cors    *CORS
+	openAPI *OpenAPI
+	sqlite  *sql.DB
+	flags   *features.Flags
 }
 
 func (suite *HTTPServerSuite) SetupTest() {
 
-	ctx := context.TODO()
+	ctx := domain.WithTenant(context.TODO(), testTenant)
+	ctx = domain.WithPrincipal(ctx, &domain.Principal{Subject: "unit-test"})
 
 	assert := assert.New(suite.T())
 
-	logger, err := logging.New()
+	cfg := logging.DefaultConfig()
+
+	logger, level, err := logging.New(cfg)
 	assert.NoError(err)
+	suite.level = level
 
-	sqlite, err := db.NewSQLite()
+	// capture redacted log output in addition to writing it
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger = zap.New(zapcore.NewTee(logger.Core(), logging.NewRedactCore(core, cfg.RedactFields)))
+	suite.logs = logs
+
+	// record spans in memory
+	suite.spans = tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(suite.spans))
+	tracing.Install(tracerProvider)
+
+	sqlite, err := db.NewSQLite(sqliteConfig)
 	assert.NoError(err)
+	suite.sqlite = sqlite
 
-	err = db.Migrate(sqlite)
+	err = db.Migrate(sqlite, sqliteConfig.MigrationsDir)
 	assert.NoError(err)
 
 	personService := domain.NewPersonService(sqlite)
@@ -69,11 +152,128 @@ func (suite *HTTPServerSuite) SetupTest() {
 	assert.NoError(err)
 	deleteUserID = deletePerson.ID
 
-	bundle := domain.NewBundle(personService)
+	apiKeyService := domain.NewAPIKeyService(sqlite, auth.NewPolicy())
+
+	idempotencyService, err := domain.NewIdempotencyService(sqlite, domain.DefaultIdempotencyConfig())
+	assert.NoError(err)
+
+	bundle := domain.NewBundle(personService, apiKeyService, idempotencyService)
 
 	server := NewHTTPServer(logger, bundle)
 
-	suite.mux = NewRouter(server)
+	jwtConfig := auth.DefaultJWTConfig()
+	jwtConfig.JWKSFile = "./testfiles/jwks.json"
+
+	jwtVerifier, err := auth.NewJWTVerifier(jwtConfig)
+	assert.NoError(err)
+
+	suite.limiter, err = NewRateLimiter(ratelimit.NewMemoryStore(), DefaultRateLimitConfig())
+	assert.NoError(err)
+
+	httpMetrics := metrics.NewHTTPMetrics()
+
+	registry, err := metrics.NewRegistry(metrics.RegistryParams{
+		Collectors: []prometheus.Collector{
+			httpMetrics,
+			metrics.NewDBStatsCollector(sqlite),
+			metrics.NewMigrationCollector(sqlite),
+		},
+	})
+	assert.NoError(err)
+
+	suite.health = health.NewRegistry(health.RegistryParams{
+		Checks: []health.Check{
+			health.NewDatabaseCheck(sqlite),
+			health.NewMigrationCheck(sqlite, sqliteConfig),
+		},
+	})
+
+	suite.cors = NewCORS(&CORSConfig{AllowedOrigins: []string{"https://app.example.com"}})
+	suite.flags = features.NewFlags(features.Config{})
+
+	graphQL, err := NewGraphQLHandler(bundle, auth.NewPolicy(), suite.limiter, suite.flags, DefaultGraphQLConfig())
+	assert.NoError(err)
+
+	// responses drifting from the contract fail the test that caused them
+	suite.openAPI, err = NewOpenAPI(&OpenAPIConfig{ValidateRequests: true, ValidateResponses: true})
+	assert.NoError(err)
+	suite.openAPI.report = func(_ *http.Request, err error) {
+		suite.T().Errorf("response violates openapi document %v", err)
+	}
+
+	suite.mux = NewRouter(
+		server,
+		auth.NewPolicy(),
+		suite.limiter,
+		jwtVerifier,
+		auth.NewAPIKeyAuthenticator(apiKeyService),
+		auth.NewCertificateAuthenticator(),
+		httpMetrics,
+		tracerProvider,
+		suite.cors,
+		graphQL,
+		suite.openAPI,
+		suite.flags,
+	)
+
+	backupConfig := *sqliteConfig
+	backupConfig.BackupDir = suite.T().TempDir()
+
+	suite.admin = NewAdminRouter(
+		DefaultAdminConfig(),
+		logger,
+		auth.NewPolicy(),
+		jwtVerifier,
+		auth.NewAPIKeyAuthenticator(apiKeyService),
+		auth.NewCertificateAuthenticator(),
+		level,
+		registry,
+		suite.health,
+		sqlite,
+		&backupConfig,
+	)
+
+	suite.grpc, err = NewGRPCServer(
+		logger,
+		bundle,
+		auth.NewPolicy(),
+		jwtVerifier,
+		auth.NewAPIKeyAuthenticator(apiKeyService),
+		auth.NewCertificateAuthenticator(),
+		suite.limiter,
+		suite.flags,
+		metrics.NewGRPCMetrics(),
+		DefaultGRPCConfig(),
+		DefaultServerConfig(),
+	)
+	assert.NoError(err)
+}
+
+// do serve request authenticated as the test tenant unless request already
+// carries credentials or tenant
+func (suite *HTTPServerSuite) do(req *http.Request) *httptest.ResponseRecorder {
+
+	if req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+validToken("unit-test"))
+	}
+
+	if req.Header.Get(TenantHeader) == "" {
+		req.Header.Set(TenantHeader, testTenant)
+	}
+
+	rr := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rr, req)
+
+	return rr
+}
+
+// doAdmin serve request on the admin listener
+func (suite *HTTPServerSuite) doAdmin(req *http.Request) *httptest.ResponseRecorder {
+
+	rr := httptest.NewRecorder()
+	suite.admin.ServeHTTP(rr, req)
+
+	return rr
 }
 
 func (suite *HTTPServerSuite) TestCreatePerson() {
@@ -114,11 +314,16 @@ func (suite *HTTPServerSuite) TestCreatePerson() {
 
 		req.Header.Add("Content-Type", "application/json")
 
-		rr := httptest.NewRecorder()
-		suite.mux.ServeHTTP(rr, req)
+		rr := suite.do(req)
 
 		assert.Equal(c.expected, rr.Code)
 	}
+
+	// emails never reach log output
+	for _, entry := range suite.logs.All() {
+		assert.NotContains(entry.Message, "testing@mailbox.com")
+		assert.NotContains(fmt.Sprint(entry.ContextMap()), "testing@mailbox.com")
+	}
 }
 
 func (suite *HTTPServerSuite) TestFetchPerson() {
@@ -131,7 +336,7 @@ func (suite *HTTPServerSuite) TestFetchPerson() {
 	}{
 		{
 			// success
-			expected: http.StatusAccepted,
+			expected: http.StatusOK,
 			endpoint: fmt.Sprintf("/api/v1/person/%d", readUserID),
 		},
 		{
@@ -142,7 +347,7 @@ func (suite *HTTPServerSuite) TestFetchPerson() {
 		{
 			// not found
 			expected: http.StatusNotFound,
-			endpoint: fmt.Sprintf("/api/v1/person/%d", 99),
+			endpoint: fmt.Sprintf("/api/v1/person/%d", readUserID+999),
 		},
 	}
 
@@ -153,14 +358,62 @@ func (suite *HTTPServerSuite) TestFetchPerson() {
 
 		req.Header.Add("Content-Type", "application/json")
 
-		rr := httptest.NewRecorder()
-
-		suite.mux.ServeHTTP(rr, req)
+		rr := suite.do(req)
 
 		assert.Equal(c.expected, rr.Code)
 	}
 }
 
+func (suite *HTTPServerSuite) TestListPersons() {
+
+	assert := assert.New(suite.T())
+
+	list := func(query, tenant string) (*domain.PersonPage, int) {
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/person?"+query, nil)
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", "Bearer "+tenantToken("unit-test", tenant))
+		req.Header.Set(TenantHeader, tenant)
+
+		rr := suite.do(req)
+		if rr.Code != http.StatusOK {
+			return nil, rr.Code
+		}
+
+		page := &domain.PersonPage{}
+		assert.NoError(json.NewDecoder(rr.Body).Decode(page))
+
+		return page, rr.Code
+	}
+
+	// pages follow the cursor of the previous page
+	page, code := list(fmt.Sprintf("limit=1&after=%d", readUserID-1), testTenant)
+	assert.Equal(http.StatusOK, code)
+	if assert.Len(page.Persons, 1) {
+		assert.Equal(readUserID, page.Persons[0].ID)
+	}
+	assert.Equal(readUserID, page.NextAfter)
+
+	page, code = list(fmt.Sprintf("limit=1&after=%d", page.NextAfter), testTenant)
+	assert.Equal(http.StatusOK, code)
+	if assert.Len(page.Persons, 1) {
+		assert.Equal(deleteUserID, page.Persons[0].ID)
+	}
+
+	// other tenants do not see the persons
+	page, code = list(fmt.Sprintf("after=%d", readUserID-1), otherTenant)
+	assert.Equal(http.StatusOK, code)
+	for _, p := range page.Persons {
+		assert.Equal(otherTenant, p.TenantID)
+	}
+
+	for _, query := range []string{"limit=0", "limit=501", "limit=abc", "after=-1"} {
+		_, code = list(query, testTenant)
+		assert.Equal(http.StatusBadRequest, code, query)
+	}
+}
+
 func (suite *HTTPServerSuite) TestUpdatePerson() {
 
 	assert := assert.New(suite.T())
@@ -210,9 +463,7 @@ func (suite *HTTPServerSuite) TestUpdatePerson() {
 		req, err := http.NewRequest(http.MethodPut, "/api/v1/person/", bytes.NewReader(updatePersonByes))
 		assert.NoError(err)
 
-		rr := httptest.NewRecorder()
-
-		suite.mux.ServeHTTP(rr, req)
+		rr := suite.do(req)
 
 		assert.Equal(c.expected, rr.Code)
 	}
@@ -250,45 +501,1378 @@ func (suite *HTTPServerSuite) TestDeletePerson() {
 
 		req.Header.Add("Content-Type", "application/json")
 
-		rr := httptest.NewRecorder()
+		rr := suite.do(req)
 
-		suite.mux.ServeHTTP(rr, req)
+		assert.Equal(c.expected, rr.Code)
+	}
+}
+
+func (suite *HTTPServerSuite) TestExportPerson() {
+
+	assert := assert.New(suite.T())
+
+	cases := []struct {
+		expected int
+		endpoint string
+	}{
+		{
+			// success
+			expected: http.StatusOK,
+			endpoint: fmt.Sprintf("/api/v1/person/%d/export", readUserID),
+		},
+		{
+			// invalid URL parameter
+			expected: http.StatusBadRequest,
+			endpoint: "/api/v1/person/xxx/export",
+		},
+		{
+			// not found
+			expected: http.StatusNotFound,
+			endpoint: fmt.Sprintf("/api/v1/person/%d/export", readUserID+999),
+		},
+	}
+
+	for _, c := range cases {
+
+		req, err := http.NewRequest(http.MethodGet, c.endpoint, nil)
+		assert.NoError(err)
+
+		rr := suite.do(req)
 
 		assert.Equal(c.expected, rr.Code)
+
+		if c.expected == http.StatusOK {
+
+			var export domain.PersonExport
+			err = json.NewDecoder(rr.Body).Decode(&export)
+			assert.NoError(err)
+
+			assert.Equal(readUserID, export.Person.ID)
+			assert.NotEmpty(export.History)
+			assert.Equal(domain.HistoryActionCreated, export.History[0].Action)
+		}
 	}
 }
 
-func (suite *HTTPServerSuite) TestHealth() {
+func (suite *HTTPServerSuite) TestErasePerson() {
 
 	assert := assert.New(suite.T())
 
 	cases := []struct {
-		code int
-		body string
+		expected int
+		endpoint string
 	}{
 		{
-			// success case
-			code: http.StatusOK,
-			body: "OK",
+			// success
+			expected: http.StatusAccepted,
+			endpoint: fmt.Sprintf("/api/v1/person/%d/erase", readUserID),
+		},
+		{
+			// invalid URL parameter
+			expected: http.StatusBadRequest,
+			endpoint: "/api/v1/person/xxx/erase",
+		},
+		{
+			// not found
+			expected: http.StatusNotFound,
+			endpoint: fmt.Sprintf("/api/v1/person/%d/erase", readUserID+999),
 		},
 	}
 
 	for _, c := range cases {
 
-		req, err := http.NewRequest(http.MethodGet, "/health", nil)
+		req, err := http.NewRequest(http.MethodPost, c.endpoint, nil)
 		assert.NoError(err)
 
-		rr := httptest.NewRecorder()
+		rr := suite.do(req)
 
-		suite.mux.ServeHTTP(rr, req)
+		assert.Equal(c.expected, rr.Code)
+	}
 
-		assert.Equal(c.code, rr.Code)
+	// personal data must no longer be present in record or history
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d/export", readUserID), nil)
+	assert.NoError(err)
 
-		body, err := io.ReadAll(rr.Body)
+	rr := suite.do(req)
+
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.NotContains(rr.Body.String(), "read.person@mailbox.com")
+
+	// nor in responses stored for idempotent retries
+	key := fmt.Sprintf("erase-%d", time.Now().UnixNano())
+
+	create := func() *httptest.ResponseRecorder {
+
+		body, err := json.Marshal(&domain.NewPerson{FirstName: "erase", LastName: "person", Email: "erase.person@mailbox.com"})
 		assert.NoError(err)
 
-		assert.Equal(c.body, string(body))
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/person/", bytes.NewReader(body))
+		assert.NoError(err)
+		req.Header.Set(IdempotencyKeyHeader, key)
+
+		return suite.do(req)
+	}
+
+	rr = create()
+	assert.Equal(http.StatusCreated, rr.Code)
+
+	var created domain.Person
+	assert.NoError(json.Unmarshal(rr.Body.Bytes(), &created))
+
+	req, err = http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/person/%d/erase", created.ID), nil)
+	assert.NoError(err)
+	assert.Equal(http.StatusAccepted, suite.do(req).Code)
+
+	var stored int
+	assert.NoError(suite.sqlite.QueryRow(`SELECT COUNT(*) FROM idempotency_keys WHERE idempotency_key = ?`, key).Scan(&stored))
+	assert.Zero(stored)
+}
+
+func (suite *HTTPServerSuite) TestTenantIsolation() {
+
+	assert := assert.New(suite.T())
+
+	updateBody, err := json.Marshal(&domain.UpdatePersonRequest{
+		UpdatePerson: &domain.UpdatePerson{
+			ID:        readUserID,
+			FirstName: "cross",
+			LastName:  "tenant",
+			Email:     "cross.tenant@mailbox.com",
+		},
+	})
+	assert.NoError(err)
+
+	cases := []struct {
+		method   string
+		endpoint string
+		body     []byte
+		tenant   string
+		expected int
+	}{
+		{
+			// read other tenant person
+			method:   http.MethodGet,
+			endpoint: fmt.Sprintf("/api/v1/person/%d", readUserID),
+			tenant:   otherTenant,
+			expected: http.StatusNotFound,
+		},
+		{
+			// update other tenant person
+			method:   http.MethodPut,
+			endpoint: "/api/v1/person/",
+			body:     updateBody,
+			tenant:   otherTenant,
+			expected: http.StatusNotFound,
+		},
+		{
+			// delete other tenant person
+			method:   http.MethodDelete,
+			endpoint: fmt.Sprintf("/api/v1/person/%d", deleteUserID),
+			tenant:   otherTenant,
+			expected: http.StatusNotFound,
+		},
+		{
+			// export other tenant person
+			method:   http.MethodGet,
+			endpoint: fmt.Sprintf("/api/v1/person/%d/export", readUserID),
+			tenant:   otherTenant,
+			expected: http.StatusNotFound,
+		},
+		{
+			// erase other tenant person
+			method:   http.MethodPost,
+			endpoint: fmt.Sprintf("/api/v1/person/%d/erase", readUserID),
+			tenant:   otherTenant,
+			expected: http.StatusNotFound,
+		},
+		{
+			// invalid tenant
+			method:   http.MethodGet,
+			endpoint: fmt.Sprintf("/api/v1/person/%d", readUserID),
+			tenant:   "not a tenant!",
+			expected: http.StatusBadRequest,
+		},
+		{
+			// owning tenant still able to read untouched person
+			method:   http.MethodGet,
+			endpoint: fmt.Sprintf("/api/v1/person/%d", deleteUserID),
+			tenant:   testTenant,
+			expected: http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+
+		req, err := http.NewRequest(c.method, c.endpoint, bytes.NewReader(c.body))
+		assert.NoError(err)
+
+		// principals of other tenants
+		req.Header.Set("Authorization", "Bearer "+tenantToken("unit-test", c.tenant))
+		req.Header.Set(TenantHeader, c.tenant)
+
+		rr := suite.do(req)
+
+		assert.Equal(c.expected, rr.Code, "%s %s", c.method, c.endpoint)
+	}
+
+	// tokens without tenant claim
+	noTenant := signToken(jwt.MapClaims{
+		"sub":   "unit-test",
+		"scope": auth.ScopePersonRead + " " + auth.ScopePersonWrite,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	// cross tenant principal
+	crossTenant := signToken(jwt.MapClaims{
+		"sub":    "support",
+		"tenant": otherTenant,
+		"scope":  auth.ScopePersonRead + " " + auth.ScopeTenantCross,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	headerCases := []struct {
+		token    string
+		tenant   string
+		expected int
+	}{
+		{
+			// header does not select a tenant for principals without one
+			token:    noTenant,
+			tenant:   testTenant,
+			expected: http.StatusForbidden,
+		},
+		{
+			token:    noTenant,
+			tenant:   otherTenant,
+			expected: http.StatusForbidden,
+		},
+		{
+			// missing tenant
+			token:    noTenant,
+			expected: http.StatusBadRequest,
+		},
+		{
+			// header must match the tenant of the principal
+			token:    validToken("unit-test"),
+			tenant:   otherTenant,
+			expected: http.StatusForbidden,
+		},
+		{
+			token:    validToken("unit-test"),
+			expected: http.StatusOK,
+		},
+		{
+			// cross tenant scope selects any tenant
+			token:    crossTenant,
+			tenant:   testTenant,
+			expected: http.StatusOK,
+		},
+		{
+			token:    crossTenant,
+			tenant:   "not a tenant!",
+			expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, c := range headerCases {
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", deleteUserID), nil)
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		if c.tenant != "" {
+			req.Header.Set(TenantHeader, c.tenant)
+		}
+
+		rr := httptest.NewRecorder()
+		suite.mux.ServeHTTP(rr, req)
+
+		assert.Equal(c.expected, rr.Code, "tenant header %q", c.tenant)
 	}
+
+	// nor list persons of another tenant
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/person", nil)
+	assert.NoError(err)
+
+	req.Header.Set("Authorization", "Bearer "+noTenant)
+	req.Header.Set(TenantHeader, otherTenant)
+
+	rr := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusForbidden, rr.Code)
+	assert.NotContains(rr.Body.String(), "cross.tenant@mailbox.com")
+}
+
+func (suite *HTTPServerSuite) TestAuthentication() {
+
+	assert := assert.New(suite.T())
+
+	cases := []struct {
+		authorization string
+		expected      int
+	}{
+		{
+			// success
+			authorization: "Bearer " + validToken("unit-test"),
+			expected:      http.StatusOK,
+		},
+		{
+			// malformed token
+			authorization: "Bearer not.a.token",
+			expected:      http.StatusUnauthorized,
+		},
+		{
+			// expired token
+			authorization: "Bearer " + signToken(jwt.MapClaims{
+				"sub": "unit-test",
+				"exp": time.Now().Add(-time.Hour).Unix(),
+			}, testSigningKey),
+			expected: http.StatusUnauthorized,
+		},
+		{
+			// token without expiry
+			authorization: "Bearer " + signToken(jwt.MapClaims{"sub": "unit-test"}, testSigningKey),
+			expected:      http.StatusUnauthorized,
+		},
+		{
+			// token signed with unknown key
+			authorization: "Bearer " + signToken(jwt.MapClaims{
+				"sub": "unit-test",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}, "some-other-signing-secret-0123456789"),
+			expected: http.StatusUnauthorized,
+		},
+		{
+			// unsupported scheme
+			authorization: "Basic dXNlcjpwYXNz",
+			expected:      http.StatusUnauthorized,
+		},
+	}
+
+	for _, c := range cases {
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", c.authorization)
+
+		rr := suite.do(req)
+
+		assert.Equal(c.expected, rr.Code)
+	}
+}
+
+func (suite *HTTPServerSuite) TestAuthorization() {
+
+	assert := assert.New(suite.T())
+
+	reader := signToken(jwt.MapClaims{
+		"sub":    "reader",
+		"tenant": testTenant,
+		"roles":  []string{auth.RoleReader},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	scoped := signToken(jwt.MapClaims{
+		"sub":    "scoped",
+		"tenant": testTenant,
+		"scope":  auth.ScopePersonRead,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	cases := []struct {
+		token    string
+		method   string
+		endpoint string
+		expected int
+	}{
+		{
+			// reader role may read
+			token:    reader,
+			method:   http.MethodGet,
+			endpoint: fmt.Sprintf("/api/v1/person/%d", readUserID),
+			expected: http.StatusOK,
+		},
+		{
+			// reader role may not delete
+			token:    reader,
+			method:   http.MethodDelete,
+			endpoint: fmt.Sprintf("/api/v1/person/%d", deleteUserID),
+			expected: http.StatusForbidden,
+		},
+		{
+			// read scope may export
+			token:    scoped,
+			method:   http.MethodGet,
+			endpoint: fmt.Sprintf("/api/v1/person/%d/export", readUserID),
+			expected: http.StatusOK,
+		},
+		{
+			// read scope may not erase
+			token:    scoped,
+			method:   http.MethodPost,
+			endpoint: fmt.Sprintf("/api/v1/person/%d/erase", readUserID),
+			expected: http.StatusForbidden,
+		},
+		{
+			// person scopes do not grant admin access
+			token:    validToken("unit-test"),
+			method:   http.MethodGet,
+			endpoint: "/api/v1/admin/apikeys/",
+			expected: http.StatusForbidden,
+		},
+	}
+
+	for _, c := range cases {
+
+		req, err := http.NewRequest(c.method, c.endpoint, nil)
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", "Bearer "+c.token)
+
+		rr := suite.do(req)
+
+		assert.Equal(c.expected, rr.Code, "%s %s", c.method, c.endpoint)
+
+		if c.expected == http.StatusForbidden {
+			assert.Equal("application/problem+json", rr.Header().Get("Content-Type"))
+
+			var p problem
+			assert.NoError(json.NewDecoder(rr.Body).Decode(&p))
+			assert.Equal(http.StatusForbidden, p.Status)
+		}
+	}
+}
+
+func (suite *HTTPServerSuite) TestOwnership() {
+
+	assert := assert.New(suite.T())
+
+	update := func(token string) int {
+
+		body, err := json.Marshal(&domain.UpdatePersonRequest{
+			UpdatePerson: &domain.UpdatePerson{
+				ID:        readUserID,
+				FirstName: "owned",
+				LastName:  "person",
+				Email:     "owned.person@mailbox.com",
+			},
+		})
+		assert.NoError(err)
+
+		req, err := http.NewRequest(http.MethodPut, "/api/v1/person/", bytes.NewReader(body))
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return suite.do(req).Code
+	}
+
+	transfer := func(token, owner string) int {
+
+		body, err := json.Marshal(&domain.TransferPerson{Owner: owner})
+		assert.NoError(err)
+
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/person/%d/transfer", readUserID), bytes.NewReader(body))
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return suite.do(req).Code
+	}
+
+	intruder := validToken("intruder")
+
+	admin := signToken(jwt.MapClaims{
+		"sub":    "admin",
+		"tenant": testTenant,
+		"roles":  []string{domain.RoleAdmin},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	// non owners may not modify
+	assert.Equal(http.StatusForbidden, update(intruder))
+	assert.Equal(http.StatusForbidden, transfer(intruder, "intruder"))
+
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/person/%d", deleteUserID), nil)
+	assert.NoError(err)
+
+	req.Header.Set("Authorization", "Bearer "+intruder)
+
+	assert.Equal(http.StatusForbidden, suite.do(req).Code)
+
+	// admins may modify any person
+	assert.Equal(http.StatusAccepted, update(admin))
+
+	// owner hands person over
+	assert.Equal(http.StatusBadRequest, transfer(validToken("unit-test"), ""))
+	assert.Equal(http.StatusAccepted, transfer(validToken("unit-test"), "intruder"))
+	assert.Equal(http.StatusForbidden, update(validToken("unit-test")))
+	assert.Equal(http.StatusAccepted, update(intruder))
+
+	// transfer recorded in history
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d/export", readUserID), nil)
+	assert.NoError(err)
+
+	rr := suite.do(req)
+	assert.Equal(http.StatusOK, rr.Code)
+
+	var export domain.PersonExport
+	assert.NoError(json.NewDecoder(rr.Body).Decode(&export))
+	assert.Equal("intruder", export.Person.Owner)
+
+	transferred := false
+	for _, h := range export.History {
+		if h.Action == domain.HistoryActionTransferred {
+			transferred = true
+			assert.Equal("unit-test", h.Actor)
+		}
+	}
+	assert.True(transferred)
+}
+
+func (suite *HTTPServerSuite) TestAPIKeys() {
+
+	assert := assert.New(suite.T())
+
+	adminToken := signToken(jwt.MapClaims{
+		"sub":    "admin",
+		"tenant": testTenant,
+		"roles":  []string{domain.RoleAdmin},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	issue := func(token string, newAPIKey *domain.NewAPIKey) (*domain.CreatedAPIKey, int) {
+
+		body, err := json.Marshal(newAPIKey)
+		assert.NoError(err)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/apikeys/", bytes.NewReader(body))
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := suite.do(req)
+		if rr.Code != http.StatusCreated {
+			return nil, rr.Code
+		}
+
+		var created domain.CreatedAPIKey
+		assert.NoError(json.NewDecoder(rr.Body).Decode(&created))
+
+		return &created, rr.Code
+	}
+
+	fetch := func(key string) int {
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", "ApiKey "+key)
+
+		return suite.do(req).Code
+	}
+
+	// invalid request
+	_, code := issue(adminToken, &domain.NewAPIKey{})
+	assert.Equal(http.StatusBadRequest, code)
+
+	// keys only carry scopes granted to the caller
+	keyAdminToken := signToken(jwt.MapClaims{
+		"sub":    "key-admin",
+		"tenant": testTenant,
+		"scope":  auth.ScopeAPIKeyAdmin + " " + auth.ScopePersonRead,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	_, code = issue(keyAdminToken, &domain.NewAPIKey{Name: "escalated", Scopes: []string{auth.ScopePersonRead, auth.ScopePersonDelete}})
+	assert.Equal(http.StatusForbidden, code)
+
+	_, code = issue(adminToken, &domain.NewAPIKey{Name: "escalated", Scopes: []string{auth.ScopeTenantCross}})
+	assert.Equal(http.StatusForbidden, code)
+
+	_, code = issue(keyAdminToken, &domain.NewAPIKey{Name: "reader", Scopes: []string{auth.ScopePersonRead}})
+	assert.Equal(http.StatusCreated, code)
+
+	created, code := issue(adminToken, &domain.NewAPIKey{Name: "machine", Scopes: []string{"person:read"}})
+	assert.Equal(http.StatusCreated, code)
+	assert.NotEmpty(created.Key)
+	assert.Equal(testTenant, created.TenantID)
+
+	assert.Equal(http.StatusOK, fetch(created.Key))
+	assert.Equal(http.StatusUnauthorized, fetch(created.Key+"x"))
+
+	// listing never exposes key material
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/admin/apikeys/", nil)
+	assert.NoError(err)
+
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+
+	rr := suite.do(req)
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.NotContains(rr.Body.String(), created.Key)
+
+	// revoked keys are rejected
+	req, err = http.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/admin/apikeys/%d", created.ID), nil)
+	assert.NoError(err)
+
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+
+	rr = suite.do(req)
+	assert.Equal(http.StatusAccepted, rr.Code)
+	assert.Equal(http.StatusUnauthorized, fetch(created.Key))
+
+	// revoking twice reports not found
+	req, err = http.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/admin/apikeys/%d", created.ID), nil)
+	assert.NoError(err)
+
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+
+	rr = suite.do(req)
+	assert.Equal(http.StatusNotFound, rr.Code)
+
+	// plaintext keys are never stored for idempotent replay
+	body, err := json.Marshal(&domain.NewAPIKey{Name: "replayed", Scopes: []string{"person:read"}})
+	assert.NoError(err)
+
+	keys := map[string]bool{}
+	for i := 0; i < 2; i++ {
+
+		req, err = http.NewRequest(http.MethodPost, "/api/v1/admin/apikeys/", bytes.NewReader(body))
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		req.Header.Set(IdempotencyKeyHeader, "issue-api-key")
+
+		rr = suite.do(req)
+		assert.Equal(http.StatusCreated, rr.Code)
+		assert.Empty(rr.Header().Get("Idempotent-Replayed"))
+
+		var issued domain.CreatedAPIKey
+		assert.NoError(json.NewDecoder(rr.Body).Decode(&issued))
+		keys[issued.Key] = true
+
+		rows, err := suite.sqlite.Query("SELECT CAST(COALESCE(body, '') AS TEXT) FROM idempotency_keys")
+		assert.NoError(err)
+
+		for rows.Next() {
+			var stored string
+			assert.NoError(rows.Scan(&stored))
+			assert.NotContains(stored, issued.Key)
+			assert.NotContains(stored, issued.Prefix)
+		}
+		assert.NoError(rows.Err())
+		assert.NoError(rows.Close())
+	}
+	assert.Len(keys, 2)
+}
+
+func (suite *HTTPServerSuite) TestTenantClaim() {
+
+	assert := assert.New(suite.T())
+
+	// header naming another tenant than the claim is rejected
+	token := signToken(jwt.MapClaims{
+		"sub":    "unit-test",
+		"tenant": otherTenant,
+		"scope":  auth.ScopePersonRead,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+	assert.NoError(err)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(TenantHeader, testTenant)
+
+	rr := suite.do(req)
+
+	assert.Equal(http.StatusForbidden, rr.Code)
+
+	// tenant claim selects the tenant without header
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+	assert.NoError(err)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rr, req)
+
+	assert.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *HTTPServerSuite) TestRateLimit() {
+
+	assert := assert.New(suite.T())
+
+	suite.limiter.SetLimits(map[string]ratelimit.Limit{
+		RateLimitWrite: {Requests: 2, Period: time.Minute},
+	})
+
+	create := func(token string) *httptest.ResponseRecorder {
+
+		body, err := json.Marshal(&domain.NewPerson{
+			FirstName: "rate",
+			LastName:  "limited",
+			Email:     "rate.limited@mailbox.com",
+		})
+		assert.NoError(err)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/person/", bytes.NewReader(body))
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return suite.do(req)
+	}
+
+	client := validToken("client-a")
+
+	rr := create(client)
+	assert.Equal(http.StatusCreated, rr.Code)
+	assert.Equal("2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal("1", rr.Header().Get("RateLimit-Remaining"))
+
+	rr = create(client)
+	assert.Equal(http.StatusCreated, rr.Code)
+	assert.Equal("0", rr.Header().Get("RateLimit-Remaining"))
+
+	rr = create(client)
+	assert.Equal(http.StatusTooManyRequests, rr.Code)
+	assert.Equal("application/problem+json", rr.Header().Get("Content-Type"))
+	assert.NotEmpty(rr.Header().Get("Retry-After"))
+
+	// buckets are kept per client
+	rr = create(validToken("client-b"))
+	assert.Equal(http.StatusCreated, rr.Code)
+
+	// groups without a limit are not limited
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+	assert.NoError(err)
+
+	rr = suite.do(req)
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.Empty(rr.Header().Get("RateLimit-Limit"))
+
+	// remote addresses are limited before authentication, failed attempts included
+	suite.limiter.SetLimits(map[string]ratelimit.Limit{
+		RateLimitIP: {Requests: 2, Period: time.Minute},
+	})
+
+	fetch := func(remoteAddr string) int {
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer invalid")
+
+		return suite.do(req).Code
+	}
+
+	assert.Equal(http.StatusUnauthorized, fetch("192.0.2.1:1234"))
+	assert.Equal(http.StatusUnauthorized, fetch("192.0.2.1:4321"))
+	assert.Equal(http.StatusTooManyRequests, fetch("192.0.2.1:1234"))
+	assert.Equal(http.StatusUnauthorized, fetch("192.0.2.2:1234"))
+}
+
+func (suite *HTTPServerSuite) TestReadOnly() {
+
+	assert := assert.New(suite.T())
+
+	suite.flags.Set(features.Config{features.ReadOnly: true})
+
+	create := func() *httptest.ResponseRecorder {
+
+		body, err := json.Marshal(&domain.NewPerson{
+			FirstName: "read",
+			LastName:  "only",
+			Email:     "read.only@mailbox.com",
+		})
+		assert.NoError(err)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/person/", bytes.NewReader(body))
+		assert.NoError(err)
+
+		return suite.do(req)
+	}
+
+	// changes are rejected by every transport
+	rr := create()
+	assert.Equal(http.StatusServiceUnavailable, rr.Code)
+	assert.Equal("application/problem+json", rr.Header().Get("Content-Type"))
+
+	resp := suite.graphQL("", fmt.Sprintf(`mutation { deletePerson(id: %d) }`, deleteUserID), nil)
+	assert.Equal(codeUnavailable, resp.code())
+
+	client := personv1.NewPersonServiceClient(suite.dialGRPC())
+
+	_, err := client.DeletePerson(grpcContext("unit-test"), &personv1.DeletePersonRequest{Id: deleteUserID})
+	assert.Equal(codes.Unavailable, status.Code(err))
+
+	// reads are served
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", deleteUserID), nil)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, suite.do(req).Code)
+
+	resp = suite.graphQL("", fmt.Sprintf(`{ person(id: %d) { id } }`, deleteUserID), nil)
+	assert.Empty(resp.Errors)
+
+	_, err = client.GetPerson(grpcContext("unit-test"), &personv1.GetPersonRequest{Id: deleteUserID})
+	assert.NoError(err)
+
+	// flag changes apply without restart
+	suite.flags.Set(features.Config{})
+
+	rr = create()
+	assert.Equal(http.StatusCreated, rr.Code)
+}
+
+func (suite *HTTPServerSuite) TestIdempotency() {
+
+	assert := assert.New(suite.T())
+
+	// test database persists between runs
+	key := fmt.Sprintf("create-%d", time.Now().UnixNano())
+
+	create := func(token, key string, newPerson *domain.NewPerson) *httptest.ResponseRecorder {
+
+		body, err := json.Marshal(newPerson)
+		assert.NoError(err)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/person/", bytes.NewReader(body))
+		assert.NoError(err)
+
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(IdempotencyKeyHeader, key)
+
+		return suite.do(req)
+	}
+
+	newPerson := &domain.NewPerson{
+		FirstName: "idempotent",
+		LastName:  "person",
+		Email:     "idempotent.person@mailbox.com",
+	}
+
+	first := create(validToken("unit-test"), key, newPerson)
+	assert.Equal(http.StatusCreated, first.Code)
+
+	var created domain.Person
+	assert.NoError(json.Unmarshal(first.Body.Bytes(), &created))
+
+	// retry replays stored response without creating another person
+	replay := create(validToken("unit-test"), key, newPerson)
+	assert.Equal(http.StatusCreated, replay.Code)
+	assert.Equal("true", replay.Header().Get("Idempotent-Replayed"))
+	assert.Equal(first.Body.String(), replay.Body.String())
+
+	// same key with a different payload is rejected
+	conflict := create(validToken("unit-test"), key, &domain.NewPerson{
+		FirstName: "different",
+		LastName:  "person",
+		Email:     "different.person@mailbox.com",
+	})
+	assert.Equal(http.StatusUnprocessableEntity, conflict.Code)
+
+	// keys are scoped per client
+	other := create(validToken("other-client"), key, newPerson)
+	assert.Equal(http.StatusCreated, other.Code)
+
+	var otherCreated domain.Person
+	assert.NoError(json.Unmarshal(other.Body.Bytes(), &otherCreated))
+	assert.NotEqual(created.ID, otherCreated.ID)
+
+	// validation failures are replayed as well
+	invalidKey := key + "-invalid"
+	assert.Equal(http.StatusBadRequest, create(validToken("unit-test"), invalidKey, &domain.NewPerson{}).Code)
+	assert.Equal(http.StatusBadRequest, create(validToken("unit-test"), invalidKey, &domain.NewPerson{}).Code)
+}
+
+func (suite *HTTPServerSuite) TestIdempotencyPanic() {
+
+	assert := assert.New(suite.T())
+
+	service, err := domain.NewIdempotencyService(suite.sqlite, domain.DefaultIdempotencyConfig())
+	assert.NoError(err)
+
+	panics := true
+	handler := idempotent(service)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panics {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	key := fmt.Sprintf("panic-%d", time.Now().UnixNano())
+
+	do := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/person/", bytes.NewReader([]byte("{}")))
+		assert.NoError(err)
+		req.Header.Set(IdempotencyKeyHeader, key)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(http.StatusInternalServerError, do().Code)
+
+	// key was released, retry is not rejected as in progress
+	panics = false
+	assert.Equal(http.StatusCreated, do().Code)
+}
+
+func (suite *HTTPServerSuite) TestIdempotencyLease() {
+
+	assert := assert.New(suite.T())
+
+	service, err := domain.NewIdempotencyService(suite.sqlite, &domain.IdempotencyConfig{
+		TTL:   time.Hour,
+		Lease: time.Second,
+	})
+	assert.NoError(err)
+
+	ctx := context.TODO()
+	key := fmt.Sprintf("lease-%d", time.Now().UnixNano())
+
+	stored, err := service.Reserve(ctx, "lease", key, "hash")
+	assert.NoError(err)
+	assert.Nil(stored)
+
+	_, err = service.Reserve(ctx, "lease", key, "hash")
+	assert.ErrorIs(err, domain.ErrIdempotencyInProgress)
+
+	// abandoned reservation is taken over once its lease expired
+	time.Sleep(time.Second * 2)
+
+	stored, err = service.Reserve(ctx, "lease", key, "hash")
+	assert.NoError(err)
+	assert.Nil(stored)
+}
+
+func (suite *HTTPServerSuite) TestRequestID() {
+
+	assert := assert.New(suite.T())
+
+	cases := []struct {
+		requestID string
+		generated bool
+	}{
+		{
+			// propagate caller supplied id
+			requestID: "caller-request-id",
+			generated: false,
+		},
+		{
+			// replace invalid id
+			requestID: "invalid request id",
+			generated: true,
+		},
+		{
+			// assign missing id
+			requestID: "",
+			generated: true,
+		},
+	}
+
+	for _, c := range cases {
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+		assert.NoError(err)
+
+		req.Header.Set(RequestIDHeader, c.requestID)
+
+		rr := suite.do(req)
+		assert.Equal(http.StatusOK, rr.Code)
+
+		id := rr.Header().Get(RequestIDHeader)
+		assert.NotEmpty(id)
+
+		if c.generated {
+			assert.NotEqual(c.requestID, id)
+		} else {
+			assert.Equal(c.requestID, id)
+		}
+	}
+}
+
+func (suite *HTTPServerSuite) TestAccessLog() {
+
+	assert := assert.New(suite.T())
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+	assert.NoError(err)
+
+	req.Header.Set(RequestIDHeader, "access-log-test")
+
+	rr := suite.do(req)
+	assert.Equal(http.StatusOK, rr.Code)
+
+	entries := suite.logs.FilterMessage("request").FilterField(zap.String("request_id", "access-log-test")).All()
+	assert.Len(entries, 1)
+	assert.Equal("http server.access", entries[0].LoggerName)
+
+	fields := entries[0].ContextMap()
+	assert.Equal(http.MethodGet, fields["method"])
+	assert.Equal("/api/v1/person/{id}", fields["route"])
+	assert.Equal(int64(http.StatusOK), fields["status"])
+	assert.Equal("unit-test", fields["principal"])
+	assert.NotZero(fields["bytes"])
+}
+
+func (suite *HTTPServerSuite) TestLogLevel() {
+
+	assert := assert.New(suite.T())
+
+	adminToken := signToken(jwt.MapClaims{
+		"sub":   "operator",
+		"roles": []string{auth.RoleOperator},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	level := func(method, token, body string) *httptest.ResponseRecorder {
+
+		req, err := http.NewRequest(method, "/admin/log/level", bytes.NewBufferString(body))
+		assert.NoError(err)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return suite.doAdmin(req)
+	}
+
+	// person scopes do not grant log administration
+	assert.Equal(http.StatusForbidden, level(http.MethodGet, validToken("unit-test"), "").Code)
+
+	// neither does the tenant admin role
+	tenantAdminToken := signToken(jwt.MapClaims{
+		"sub":   "admin",
+		"roles": []string{domain.RoleAdmin},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+	assert.Equal(http.StatusForbidden, level(http.MethodGet, tenantAdminToken, "").Code)
+
+	rr := level(http.MethodGet, adminToken, "")
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.JSONEq(`{"level":"info"}`, rr.Body.String())
+
+	rr = level(http.MethodPut, adminToken, `{"level":"debug"}`)
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.Equal(zapcore.DebugLevel, suite.level.Level())
+
+	rr = level(http.MethodPut, adminToken, `{"level":"verbose"}`)
+	assert.Equal(http.StatusBadRequest, rr.Code)
+	assert.Equal(zapcore.DebugLevel, suite.level.Level())
+}
+
+func (suite *HTTPServerSuite) TestMetrics() {
+
+	assert := assert.New(suite.T())
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+	assert.NoError(err)
+	suite.do(req)
+
+	req, err = http.NewRequest(http.MethodGet, "/metrics", nil)
+	assert.NoError(err)
+
+	rr := suite.doAdmin(req)
+	assert.Equal(http.StatusOK, rr.Code)
+
+	body := rr.Body.String()
+
+	// labelled by route pattern, never by raw path
+	assert.Contains(body, `http_requests_total{method="GET",route="/api/v1/person/{id}",status="200"} 1`)
+	assert.Contains(body, `http_request_duration_seconds_bucket{method="GET",route="/api/v1/person/{id}",status="200"`)
+	assert.NotContains(body, fmt.Sprintf("/api/v1/person/%d", readUserID))
+
+	assert.Contains(body, `go_sql_open_connections{db_name="sqlite"}`)
+	assert.Contains(body, "db_migration_version 6")
+	assert.Contains(body, "db_migration_dirty 0")
+	assert.Contains(body, "go_build_info")
+	assert.Contains(body, "go_goroutines")
+}
+
+func (suite *HTTPServerSuite) TestTracing() {
+
+	assert := assert.New(suite.T())
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+	assert.NoError(err)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	rr := suite.do(req)
+	assert.Equal(http.StatusOK, rr.Code)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range suite.spans.Ended() {
+		if s.SpanContext().TraceID().String() == traceID {
+			spans[s.Name()] = s
+		}
+	}
+
+	server, ok := spans["GET /api/v1/person/{id}"]
+	assert.True(ok)
+	assert.Equal(trace.SpanKindServer, server.SpanKind())
+	assert.Equal("00f067aa0ba902b7", server.Parent().SpanID().String())
+
+	service, ok := spans["PersonService.Read"]
+	assert.True(ok)
+	assert.Equal(server.SpanContext().SpanID(), service.Parent().SpanID())
+
+	query, ok := spans["ReadPerson"]
+	assert.True(ok)
+	assert.Equal(trace.SpanKindClient, query.SpanKind())
+	assert.Equal(service.SpanContext().SpanID(), query.Parent().SpanID())
+
+	// log lines carry the trace id
+	found := false
+	for _, entry := range suite.logs.FilterMessage("request").All() {
+		if entry.ContextMap()["trace_id"] == traceID {
+			found = true
+		}
+	}
+	assert.True(found)
+}
+
+func (suite *HTTPServerSuite) TestHealth() {
+
+	assert := assert.New(suite.T())
+
+	cases := []struct {
+		code int
+		body string
+	}{
+		{
+			// success case
+			code: http.StatusOK,
+			body: "OK",
+		},
+	}
+
+	for _, c := range cases {
+
+		req, err := http.NewRequest(http.MethodGet, "/health", nil)
+		assert.NoError(err)
+
+		rr := suite.do(req)
+
+		assert.Equal(c.code, rr.Code)
+
+		body, err := io.ReadAll(rr.Body)
+		assert.NoError(err)
+
+		assert.Equal(c.body, string(body))
+	}
+}
+
+func (suite *HTTPServerSuite) TestProbes() {
+
+	assert := assert.New(suite.T())
+
+	probe := func(path string) (int, *health.Report) {
+
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		assert.NoError(err)
+
+		rr := suite.doAdmin(req)
+
+		report := &health.Report{}
+		assert.NoError(json.NewDecoder(rr.Body).Decode(report))
+
+		return rr.Code, report
+	}
+
+	code, report := probe("/livez")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(health.StatusOK, report.Status)
+
+	code, report = probe("/readyz")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(health.StatusOK, report.Status)
+	assert.Equal(health.StatusOK, report.Checks["sqlite"].Status)
+	assert.Equal(health.StatusOK, report.Checks["migrations"].Status)
+	assert.NotEmpty(report.Checks["sqlite"].Latency)
+
+	// startup fails until marked started
+	code, report = probe("/startupz")
+	assert.Equal(http.StatusServiceUnavailable, code)
+	assert.Equal(health.ErrNotStarted.Error(), report.Checks["startup"].Error)
+
+	suite.health.MarkStarted()
+
+	code, _ = probe("/startupz")
+	assert.Equal(http.StatusOK, code)
+
+	// non critical failures degrade without failing the probe
+	suite.health.Register(health.Check{
+		Name:  "cache",
+		Probe: health.Readiness,
+		Func:  func(context.Context) error { return errors.New("connection refused") },
+	})
+
+	code, report = probe("/readyz")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(health.StatusDegraded, report.Status)
+	assert.Equal("connection refused", report.Checks["cache"].Error)
+
+	suite.health.Register(health.Check{
+		Name:     "queue",
+		Probe:    health.Readiness,
+		Critical: true,
+		Func:     func(context.Context) error { return errors.New("unreachable") },
+	})
+
+	code, report = probe("/readyz")
+	assert.Equal(http.StatusServiceUnavailable, code)
+	assert.Equal(health.StatusFail, report.Status)
+}
+
+func (suite *HTTPServerSuite) TestCORS() {
+
+	assert := assert.New(suite.T())
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/person/", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rr := httptest.NewRecorder()
+		suite.mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := preflight("https://app.example.com")
+	assert.Equal(http.StatusNoContent, rr.Code)
+	assert.Equal("https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(rr.Header().Get("Access-Control-Allow-Headers"), IdempotencyKeyHeader)
+
+	rr = preflight("https://evil.example.com")
+	assert.Equal(http.StatusForbidden, rr.Code)
+	assert.Empty(rr.Header().Get("Access-Control-Allow-Origin"))
+
+	// origins replaced at runtime
+	suite.cors.SetAllowedOrigins([]string{"https://evil.example.com"})
+
+	assert.Equal(http.StatusForbidden, preflight("https://app.example.com").Code)
+	assert.Equal(http.StatusNoContent, preflight("https://evil.example.com").Code)
+
+	// actual requests of allowed origins are annotated
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/person/%d", readUserID), nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rr = suite.do(req)
+	assert.Equal("https://evil.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func (suite *HTTPServerSuite) TestAdminListener() {
+
+	assert := assert.New(suite.T())
+
+	operatorToken := signToken(jwt.MapClaims{
+		"sub":   "operator",
+		"roles": []string{auth.RoleOperator},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	tenantAdminToken := signToken(jwt.MapClaims{
+		"sub":   "admin",
+		"roles": []string{domain.RoleAdmin},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}, testSigningKey)
+
+	// operational endpoints are not served on the public listener
+	for _, path := range []string{"/metrics", "/readyz", "/debug/pprof/", "/admin/log/level"} {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		assert.NoError(err)
+		assert.Equal(http.StatusNotFound, suite.do(req).Code, path)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	assert.NoError(err)
+
+	rr := suite.doAdmin(req)
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.Contains(rr.Body.String(), "goroutine")
+
+	backup := func(token string) *httptest.ResponseRecorder {
+
+		req, err := http.NewRequest(http.MethodPost, "/admin/backup", nil)
+		assert.NoError(err)
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		return suite.doAdmin(req)
+	}
+
+	assert.Equal(http.StatusUnauthorized, backup("").Code)
+	assert.Equal(http.StatusForbidden, backup(validToken("unit-test")).Code)
+	assert.Equal(http.StatusForbidden, backup(tenantAdminToken).Code)
+
+	rr = backup(operatorToken)
+	assert.Equal(http.StatusCreated, rr.Code)
+
+	var result map[string]string
+	assert.NoError(json.NewDecoder(rr.Body).Decode(&result))
+
+	info, err := os.Stat(result["path"])
+	assert.NoError(err)
+	assert.NotZero(info.Size())
+}
+
+func (suite *HTTPServerSuite) TestAdminListenerRemote() {
+
+	assert := assert.New(suite.T())
+
+	jwtConfig := auth.DefaultJWTConfig()
+	jwtConfig.JWKSFile = "./testfiles/jwks.json"
+
+	jwtVerifier, err := auth.NewJWTVerifier(jwtConfig)
+	assert.NoError(err)
+
+	cfg := DefaultAdminConfig()
+	cfg.LocalOnly = false
+
+	admin := NewAdminRouter(
+		cfg,
+		zap.NewNop(),
+		auth.NewPolicy(),
+		jwtVerifier,
+		auth.NewAPIKeyAuthenticator(domain.NewAPIKeyService(suite.sqlite, auth.NewPolicy())),
+		auth.NewCertificateAuthenticator(),
+		suite.level,
+		prometheus.NewRegistry(),
+		suite.health,
+		suite.sqlite,
+		&db.Config{},
+	)
+
+	get := func(path string, roles ...string) int {
+
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		assert.NoError(err)
+
+		if len(roles) > 0 {
+			req.Header.Set("Authorization", "Bearer "+signToken(jwt.MapClaims{
+				"sub":   "remote",
+				"roles": roles,
+				"exp":   time.Now().Add(time.Hour).Unix(),
+			}, testSigningKey))
+		}
+
+		rr := httptest.NewRecorder()
+		admin.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// reachable beyond localhost metrics and profiles require the operator role
+	for _, path := range []string{"/metrics", "/debug/pprof/"} {
+		assert.Equal(http.StatusUnauthorized, get(path), path)
+		assert.Equal(http.StatusForbidden, get(path, domain.RoleAdmin), path)
+		assert.Equal(http.StatusOK, get(path, auth.RoleOperator), path)
+	}
+
+	assert.Equal(http.StatusOK, get("/livez"))
 }
 
 func TestHttpServerSuite(t *testing.T) {
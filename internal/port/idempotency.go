@@ -0,0 +1,139 @@
+package port
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/logging"
+)
+
+const (
+	// IdempotencyKeyHeader request header carrying client supplied idempotency key
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodySize   = 1 << 20
+)
+
+// idempotent middleware replaying the stored response of POST requests retried
+// with the same Idempotency-Key header
+func idempotent(service *domain.IdempotencyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			log := logging.FromContext(r.Context()).Sugar()
+
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				writeProblem(w, r, http.StatusBadRequest, "idempotency key too long")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+			if err != nil {
+				writeProblem(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			tenantID, _ := domain.TenantFromContext(r.Context())
+			scope := domain.IdempotencyScope(tenantID, clientKey(r), r.Method+" "+r.URL.Path)
+
+			sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+			hash := hex.EncodeToString(sum[:])
+
+			stored, err := service.Reserve(r.Context(), scope, key, hash)
+			if errors.Is(err, domain.ErrIdempotencyMismatch) {
+				writeProblem(w, r, http.StatusUnprocessableEntity, "idempotency key already used with a different request")
+				return
+			} else if errors.Is(err, domain.ErrIdempotencyInProgress) {
+				writeProblem(w, r, http.StatusConflict, "request with this idempotency key is still in progress")
+				return
+			} else if err != nil {
+				log.Errorf("unable to reserve idempotency key %v", err)
+				http.Error(w, "unable to process idempotency key", http.StatusInternalServerError)
+				return
+			}
+
+			if stored != nil {
+				w.Header().Set("Content-Type", stored.ContentType)
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.Status)
+				_, _ = w.Write(stored.Body)
+				return
+			}
+
+			// the request context may already be cancelled, the outcome must still be recorded
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			release := func() {
+				if err := service.Release(ctx, scope, key); err != nil {
+					log.Errorf("unable to release idempotency key %v", err)
+				}
+			}
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+
+			// a panicking handler must not leave the key in progress until its lease expires
+			defer func() {
+				if rec := recover(); rec != nil {
+					log.Errorf("request with idempotency key panicked %v", rec)
+					release()
+					if ww.Status() == 0 {
+						writeProblem(ww, r, http.StatusInternalServerError, "internal server error")
+					}
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if !replayable(status) {
+				release()
+				return
+			}
+
+			err = service.Complete(ctx, scope, key, &domain.StoredResponse{
+				Status:      status,
+				ContentType: ww.Header().Get("Content-Type"),
+				Body:        buf.Bytes(),
+			})
+			if err != nil {
+				log.Errorf("unable to store idempotent response %v", err)
+			}
+		})
+	}
+}
+
+// replayable responses which do not depend on transient server or caller state
+func replayable(status int) bool {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return false
+	case status == http.StatusUnauthorized, status == http.StatusForbidden, status == http.StatusTooManyRequests:
+		return false
+	default:
+		return true
+	}
+}
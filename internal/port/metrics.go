@@ -0,0 +1,37 @@
+package port
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/trevatk/go-template/internal/metrics"
+)
+
+// instrument middleware recording request count and latency by matched route pattern
+func instrument(httpMetrics *metrics.HTTPMetrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			start := time.Now()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			// unmatched paths share one label value
+			route := routePattern(r)
+			if route == "" {
+				route = "unmatched"
+			}
+
+			httpMetrics.Observe(r.Method, route, status, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package port
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/trevatk/go-template/internal/auth"
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/features"
+	"github.com/trevatk/go-template/internal/logging"
+)
+
+const (
+	// TenantHeader request header used to scope requests to a tenant
+	TenantHeader = "X-Tenant-ID"
+)
+
+var (
+	tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+	errReadOnly = errors.New("persons are read only, changes are temporarily disabled")
+)
+
+// authenticate middleware identifying the caller using the first authenticator
+// able to handle the request credentials, unauthenticated requests are rejected
+func authenticate(authenticators ...auth.Authenticator) func(http.Handler) http.Handler {
+
+	schemes := make([]string, 0, len(authenticators))
+	for _, a := range authenticators {
+		schemes = append(schemes, a.Scheme())
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			for _, a := range authenticators {
+
+				principal, err := a.Authenticate(r)
+				if errors.Is(err, auth.ErrNoCredentials) {
+					continue
+				} else if errors.Is(err, auth.ErrInvalidCredentials) {
+					w.Header().Set("WWW-Authenticate", a.Scheme()+` error="invalid_token"`)
+					writeProblem(w, r, http.StatusUnauthorized, "invalid credentials")
+					return
+				} else if err != nil {
+					logging.FromContext(r.Context()).Sugar().Errorf("unable to authenticate request %v", err)
+					http.Error(w, "unable to authenticate request", http.StatusInternalServerError)
+					return
+				}
+
+				recordPrincipal(r.Context(), principal)
+
+				ctx := domain.WithPrincipal(r.Context(), principal)
+				ctx = logging.WithContext(ctx, logging.FromContext(ctx).With(zap.String("principal", principal.Subject)))
+
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			w.Header().Set("WWW-Authenticate", strings.Join(schemes, ", "))
+			writeProblem(w, r, http.StatusUnauthorized, "authentication required")
+		})
+	}
+}
+
+// authorize middleware rejecting principals not granted all scopes by policy
+func authorize(policy *auth.Policy, scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			principal, _ := domain.PrincipalFromContext(r.Context())
+
+			for _, scope := range scopes {
+				if !policy.Allows(principal, scope) {
+					writeProblem(w, r, http.StatusForbidden, "missing required scope "+scope)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var (
+	errNoTenant      = errors.New("tenant not provided")
+	errInvalidTenant = errors.New("invalid tenant")
+	errTenantDenied  = errors.New("selecting a tenant requires scope " + auth.ScopeTenantCross)
+)
+
+// requestTenant tenant of an authenticated request, taken from the principal. The tenant
+// header only selects a tenant for principals granted ScopeTenantCross, otherwise it must
+// match the tenant of the principal.
+func requestTenant(policy *auth.Policy, principal *domain.Principal, header string) (string, error) {
+
+	var tenantID string
+
+	switch {
+	case header != "" && policy.Allows(principal, auth.ScopeTenantCross):
+		tenantID = header
+	case principal != nil && principal.TenantID != "":
+		if header != "" && header != principal.TenantID {
+			return "", errTenantDenied
+		}
+		tenantID = principal.TenantID
+	case header != "":
+		return "", errTenantDenied
+	default:
+		return "", errNoTenant
+	}
+
+	if !tenantPattern.MatchString(tenantID) {
+		return "", errInvalidTenant
+	}
+
+	return tenantID, nil
+}
+
+// readOnly middleware rejecting requests while the read only feature flag is enabled
+func readOnly(flags *features.Flags) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			if flags.Enabled(features.ReadOnly) {
+				writeProblem(w, r, http.StatusServiceUnavailable, errReadOnly.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// resolveTenant middleware scoping request context to the tenant of the principal
+func resolveTenant(policy *auth.Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			principal, _ := domain.PrincipalFromContext(r.Context())
+
+			tenantID, err := requestTenant(policy, principal, r.Header.Get(TenantHeader))
+			if errors.Is(err, errTenantDenied) {
+				writeProblem(w, r, http.StatusForbidden, err.Error())
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(domain.WithTenant(r.Context(), tenantID)))
+		})
+	}
+}
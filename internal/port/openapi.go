@@ -0,0 +1,152 @@
+package port
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"gopkg.in/yaml.v3"
+
+	"github.com/trevatk/go-template/api"
+	"github.com/trevatk/go-template/internal/logging"
+)
+
+// OpenAPIConfig validation of http api traffic against the OpenAPI document
+type OpenAPIConfig struct {
+	// ValidateRequests reject requests violating the document before they reach a handler
+	ValidateRequests bool `yaml:"validate_requests" env:"OPENAPI_VALIDATE_REQUESTS"`
+	// ValidateResponses report responses violating the document, bodies are buffered so
+	// this is meant for tests and staging
+	ValidateResponses bool `yaml:"validate_responses" env:"OPENAPI_VALIDATE_RESPONSES"`
+}
+
+// DefaultOpenAPIConfig requests are validated, responses are not
+func DefaultOpenAPIConfig() *OpenAPIConfig {
+	return &OpenAPIConfig{ValidateRequests: true}
+}
+
+// Validate check configuration values
+func (c *OpenAPIConfig) Validate() error {
+	return nil
+}
+
+// openAPIDocument parts of the OpenAPI document the service relies on
+type openAPIDocument struct {
+	OpenAPI string `yaml:"openapi"`
+	// Paths path items keyed by path, each keyed by lower case method or parameters
+	Paths      map[string]map[string]interface{} `yaml:"paths"`
+	Components struct {
+		Schemas map[string]*openAPISchema `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// openAPISchema subset of a json schema describing models
+type openAPISchema struct {
+	Ref        string                    `yaml:"$ref"`
+	Type       string                    `yaml:"type"`
+	Format     string                    `yaml:"format"`
+	Required   []string                  `yaml:"required"`
+	Properties map[string]*openAPISchema `yaml:"properties"`
+	Items      *openAPISchema            `yaml:"items"`
+	AllOf      []*openAPISchema          `yaml:"allOf"`
+	OneOf      []*openAPISchema          `yaml:"oneOf"`
+}
+
+// OpenAPI contract of the http api
+type OpenAPI struct {
+	cfg  *OpenAPIConfig
+	doc  *openAPIDocument
+	json []byte
+	// operations compiled validation rules keyed by method and path
+	operations map[string]*openAPIOperation
+	// report response violations, logged unless replaced by tests
+	report func(r *http.Request, err error)
+}
+
+// NewOpenAPI parse embedded OpenAPI document and compile its schemas
+func NewOpenAPI(cfg *OpenAPIConfig) (*OpenAPI, error) {
+
+	var raw interface{}
+	if err := yaml.Unmarshal(api.OpenAPI, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi document %v", err)
+	}
+
+	// served as json, status code keys must be quoted to convert
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert openapi document to json %v", err)
+	}
+
+	doc := &openAPIDocument{}
+	if err := yaml.Unmarshal(api.OpenAPI, doc); err != nil {
+		return nil, fmt.Errorf("failed to decode openapi document %v", err)
+	}
+
+	operations, err := compileOperations(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAPI{
+		cfg:        cfg,
+		doc:        doc,
+		json:       data,
+		operations: operations,
+		report: func(r *http.Request, err error) {
+			logging.FromContext(r.Context()).Sugar().Warnf("response violates openapi document %v", err)
+		},
+	}, nil
+}
+
+// serveDocument handler serving the OpenAPI document as json
+func (o *OpenAPI) serveDocument(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(o.json); err != nil {
+		logging.FromContext(r.Context()).Sugar().Errorf("failed to write openapi document %v", err)
+	}
+}
+
+// docsAssets swagger-ui files loaded by the docs page
+var docsAssets = map[string]string{
+	"swagger-ui.css":       "text/css; charset=utf-8",
+	"swagger-ui-bundle.js": "text/javascript; charset=utf-8",
+}
+
+// serveDocsAsset handler serving the embedded swagger-ui files of the docs page
+func (o *OpenAPI) serveDocsAsset(w http.ResponseWriter, r *http.Request) {
+
+	asset := chi.URLParam(r, "asset")
+
+	contentType, ok := docsAssets[asset]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := fs.ReadFile(api.DocsAssets, asset)
+	if err != nil {
+		logging.FromContext(r.Context()).Sugar().Errorf("failed to read docs asset %v", err)
+		http.Error(w, "failed to read docs asset", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		logging.FromContext(r.Context()).Sugar().Errorf("failed to write docs asset %v", err)
+	}
+}
+
+// serveDocs handler serving the docs page rendering the OpenAPI document
+func (o *OpenAPI) serveDocs(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(api.Docs); err != nil {
+		logging.FromContext(r.Context()).Sugar().Errorf("failed to write docs page %v", err)
+	}
+}
@@ -0,0 +1,418 @@
+package port
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/trevatk/go-template/client"
+	"github.com/trevatk/go-template/internal/domain"
+)
+
+// openAPIModels go models of the component schemas, including the models of the client
+// package, fields of response models without omitempty must be required
+var openAPIModels = []struct {
+	schema   string
+	model    interface{}
+	response bool
+}{
+	{"NewPerson", domain.NewPerson{}, false},
+	{"UpdatePerson", domain.UpdatePerson{}, false},
+	{"TransferPerson", domain.TransferPerson{}, false},
+	{"Person", domain.Person{}, true},
+	{"PersonPage", domain.PersonPage{}, true},
+	{"PersonHistory", domain.PersonHistory{}, true},
+	{"PersonExport", domain.PersonExport{}, true},
+	{"NewAPIKey", domain.NewAPIKey{}, false},
+	{"APIKey", domain.APIKey{}, true},
+	{"CreatedAPIKey", domain.CreatedAPIKey{}, true},
+	{"Problem", problem{}, true},
+	{"NewPerson", client.NewPerson{}, false},
+	{"UpdatePerson", client.UpdatePerson{}, false},
+	{"Person", client.Person{}, true},
+	{"PersonPage", client.PersonPage{}, true},
+}
+
+func (suite *HTTPServerSuite) TestOpenAPI() {
+
+	assert := assert.New(suite.T())
+
+	routes := map[string]bool{}
+
+	// every route of the router is documented
+	err := chi.Walk(suite.mux, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+
+		routes[method+" "+route] = true
+
+		item, ok := suite.openAPI.doc.Paths[route]
+		if assert.True(ok, "route %s has no path in api/openapi.yaml", route) {
+			_, ok = item[strings.ToLower(method)]
+			assert.True(ok, "route %s %s has no operation in api/openapi.yaml", method, route)
+		}
+
+		return nil
+	})
+	assert.NoError(err)
+
+	// and every documented operation is routed
+	for path, item := range suite.openAPI.doc.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			assert.True(routes[strings.ToUpper(method)+" "+path], "operation %s %s is not routed", method, path)
+		}
+	}
+
+	// document and docs are served without credentials
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rr, req)
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.Equal("application/json", rr.Header().Get("Content-Type"))
+
+	var doc struct {
+		OpenAPI string                 `json:"openapi"`
+		Paths   map[string]interface{} `json:"paths"`
+	}
+	assert.NoError(json.NewDecoder(rr.Body).Decode(&doc))
+	assert.Equal("3.1.0", doc.OpenAPI)
+	assert.Contains(doc.Paths, "/api/v1/person/{id}")
+
+	req = httptest.NewRequest(http.MethodGet, "/docs", nil)
+	rr = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rr, req)
+	assert.Equal(http.StatusOK, rr.Code)
+	assert.Contains(rr.Body.String(), `url: "openapi.json"`)
+	assert.NotContains(rr.Body.String(), "https://")
+
+	// ui assets are embedded
+	for asset, contentType := range docsAssets {
+		req = httptest.NewRequest(http.MethodGet, "/docs/"+asset, nil)
+		rr = httptest.NewRecorder()
+		suite.mux.ServeHTTP(rr, req)
+		assert.Equal(http.StatusOK, rr.Code, asset)
+		assert.Equal(contentType, rr.Header().Get("Content-Type"))
+		assert.NotEmpty(rr.Body.Bytes())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/docs/index.html", nil)
+	rr = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rr, req)
+	assert.Equal(http.StatusNotFound, rr.Code)
+}
+
+func (suite *HTTPServerSuite) TestOpenAPIValidation() {
+
+	assert := assert.New(suite.T())
+
+	cases := []struct {
+		method   string
+		endpoint string
+		body     string
+		header   map[string]string
+		detail   string
+	}{
+		{
+			// path parameter below minimum
+			method:   http.MethodGet,
+			endpoint: "/api/v1/person/0",
+			detail:   "invalid path parameter id",
+		},
+		{
+			// path parameter of wrong type
+			method:   http.MethodDelete,
+			endpoint: "/api/v1/person/abc",
+			detail:   "invalid path parameter id: expected integer",
+		},
+		{
+			// missing required property
+			method:   http.MethodPost,
+			endpoint: "/api/v1/person",
+			body:     `{"first_name":"open","last_name":"api"}`,
+			detail:   "invalid request body: missing properties: 'email'",
+		},
+		{
+			// property violating constraint
+			method:   http.MethodPost,
+			endpoint: "/api/v1/person",
+			body:     `{"first_name":"","last_name":"api","email":"open.api@mailbox.com"}`,
+			detail:   "invalid request body: /first_name",
+		},
+		{
+			// property of wrong type
+			method:   http.MethodPut,
+			endpoint: "/api/v1/person",
+			body:     `{"id":"1"}`,
+			detail:   "invalid request body: /id",
+		},
+		{
+			// malformed body
+			method:   http.MethodPut,
+			endpoint: "/api/v1/person",
+			body:     `{"id":`,
+			detail:   "invalid request body: malformed json",
+		},
+		{
+			// missing required body
+			method:   http.MethodPost,
+			endpoint: fmt.Sprintf("/api/v1/person/%d/transfer", readUserID),
+			detail:   "missing request body",
+		},
+		{
+			// header parameter violating constraint
+			method:   http.MethodPost,
+			endpoint: "/api/v1/person",
+			body:     `{"first_name":"open","last_name":"api","email":"open.api@mailbox.com"}`,
+			header:   map[string]string{IdempotencyKeyHeader: strings.Repeat("k", 256)},
+			detail:   "invalid header parameter Idempotency-Key",
+		},
+	}
+
+	for _, c := range cases {
+
+		req, err := http.NewRequest(c.method, c.endpoint, strings.NewReader(c.body))
+		assert.NoError(err)
+
+		for k, v := range c.header {
+			req.Header.Set(k, v)
+		}
+
+		rr := suite.do(req)
+
+		assert.Equal(http.StatusBadRequest, rr.Code, "%s %s", c.method, c.endpoint)
+		assert.Equal("application/problem+json", rr.Header().Get("Content-Type"))
+
+		p := &problem{}
+		assert.NoError(json.NewDecoder(rr.Body).Decode(p))
+		assert.Contains(p.Detail, c.detail, "%s %s", c.method, c.endpoint)
+	}
+}
+
+func TestOpenAPIResponseValidation(t *testing.T) {
+
+	assert := assert.New(t)
+
+	openAPI, err := NewOpenAPI(&OpenAPIConfig{ValidateResponses: true})
+	assert.NoError(err)
+
+	var violations []string
+	openAPI.report = func(_ *http.Request, err error) {
+		violations = append(violations, err.Error())
+	}
+
+	reply := func(status int, contentType, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}
+	}
+
+	// handlers drifting from the contract
+	r := chi.NewRouter()
+	r.Use(openAPI.validate)
+	r.Get("/api/v1/person/{id}", reply(http.StatusAccepted, "application/json", `{}`))
+	r.Put("/api/v1/person", reply(http.StatusAccepted, "text/plain", "updated"))
+	r.Delete("/api/v1/person/{id}", reply(http.StatusAccepted, "application/json", `"DONE"`))
+	r.Get("/api/v1/person/{id}/export", reply(http.StatusOK, "application/json", `{"history":[]}`))
+	r.Post("/api/v1/person/{id}/erase", reply(http.StatusInternalServerError, "text/plain", "failed"))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/person/1", nil),
+		httptest.NewRequest(http.MethodPut, "/api/v1/person", strings.NewReader(`{"id":1}`)),
+		httptest.NewRequest(http.MethodDelete, "/api/v1/person/1", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/person/1/export", nil),
+		httptest.NewRequest(http.MethodPost, "/api/v1/person/1/erase", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal([]string{
+		"GET /api/v1/person/{id} undocumented status 202",
+		"PUT /api/v1/person undocumented content type text/plain of status 202",
+		"DELETE /api/v1/person/{id} invalid body of status 202: value must be \"SUCCESS\"",
+		"GET /api/v1/person/{id}/export invalid body of status 200: missing properties: 'person', 'exported_at'",
+	}, violations)
+}
+
+func TestOpenAPIModels(t *testing.T) {
+
+	assert := assert.New(t)
+
+	openAPI, err := NewOpenAPI(DefaultOpenAPIConfig())
+	assert.NoError(err)
+
+	schemas := openAPI.doc.Components.Schemas
+
+	for _, m := range openAPIModels {
+
+		name := reflect.TypeOf(m.model).String()
+
+		schema, ok := schemas[m.schema]
+		if !assert.True(ok, "schema %s of %s missing", m.schema, name) {
+			continue
+		}
+
+		properties, required := flattenSchema(schemas, schema)
+
+		fields := jsonFields(reflect.TypeOf(m.model))
+
+		assert.ElementsMatch(sortedKeys(fields), sortedKeys(properties), "properties of %s", name)
+
+		for field, f := range fields {
+
+			property, ok := properties[field]
+			if !ok {
+				continue
+			}
+
+			assert.Equal(jsonSchemaType(f.typ), schemaType(schemas, property), "type of %s.%s", name, field)
+
+			if m.response {
+				assert.Equal(!f.omitempty, required[field], "%s.%s required", name, field)
+			}
+		}
+	}
+}
+
+// jsonField json encoded struct field
+type jsonField struct {
+	typ       reflect.Type
+	omitempty bool
+}
+
+// jsonFields json encoded fields of struct t, embedded structs are flattened
+func jsonFields(t reflect.Type) map[string]jsonField {
+
+	fields := map[string]jsonField{}
+
+	for i := 0; i < t.NumField(); i++ {
+
+		f := t.Field(i)
+
+		if f.Anonymous {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			for name, field := range jsonFields(embedded) {
+				fields[name] = field
+			}
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" || !f.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+
+		fields[name] = jsonField{typ: f.Type, omitempty: strings.Contains(options, "omitempty")}
+	}
+
+	return fields
+}
+
+// jsonSchemaType json schema type of values of t, empty for arbitrary json
+func jsonSchemaType(t reflect.Type) string {
+
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		return ""
+	} else if t == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchemaType(t.Elem())
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+
+	return t.Kind().String()
+}
+
+// schemaType type of schema following references and nullable unions
+func schemaType(schemas map[string]*openAPISchema, schema *openAPISchema) string {
+
+	if schema.Ref != "" {
+		return schemaType(schemas, schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")])
+	}
+
+	for _, s := range schema.OneOf {
+		if s.Type != "null" {
+			return schemaType(schemas, s)
+		}
+	}
+
+	return schema.Type
+}
+
+// flattenSchema properties and required properties of schema including all of its allOf parts
+func flattenSchema(schemas map[string]*openAPISchema, schema *openAPISchema) (map[string]*openAPISchema, map[string]bool) {
+
+	if schema.Ref != "" {
+		return flattenSchema(schemas, schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")])
+	}
+
+	properties := map[string]*openAPISchema{}
+	required := map[string]bool{}
+
+	for name, p := range schema.Properties {
+		properties[name] = p
+	}
+
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	for _, part := range schema.AllOf {
+		p, r := flattenSchema(schemas, part)
+		for name, s := range p {
+			properties[name] = s
+		}
+		for name := range r {
+			required[name] = true
+		}
+	}
+
+	return properties, required
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,448 @@
+package port
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const (
+	// openAPIResource url the document is registered under with the schema compiler
+	openAPIResource = "openapi.json"
+
+	maxValidatedBodySize = 1 << 20
+)
+
+// openAPIMethods path item keys holding operations
+var openAPIMethods = map[string]bool{
+	"get":    true,
+	"put":    true,
+	"post":   true,
+	"delete": true,
+	"patch":  true,
+}
+
+// openAPIOperation compiled validation rules of an operation
+type openAPIOperation struct {
+	params       []*openAPIParameter
+	body         *jsonschema.Schema
+	bodyRequired bool
+	// responses schemas keyed by status and media type, nil schemas accept any body
+	responses map[string]map[string]*jsonschema.Schema
+}
+
+// openAPIParameter compiled path, query or header parameter
+type openAPIParameter struct {
+	name     string
+	in       string
+	required bool
+	// typ json schema type raw values are converted to before validation
+	typ    string
+	schema *jsonschema.Schema
+}
+
+// compileOperations compile the schemas of every operation of the json encoded document
+func compileOperations(data []byte) (map[string]*openAPIOperation, error) {
+
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to decode openapi document %v", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+
+	if err := compiler.AddResource(openAPIResource, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to add openapi document to schema compiler %v", err)
+	}
+
+	compile := func(ptr string) (*jsonschema.Schema, error) {
+		schema, err := compiler.Compile(openAPIResource + ptr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile schema %s %v", ptr, err)
+		}
+		return schema, nil
+	}
+
+	operations := map[string]*openAPIOperation{}
+
+	paths, _ := root["paths"].(map[string]interface{})
+	for path, item := range paths {
+
+		itemNode, _ := item.(map[string]interface{})
+		itemPtr := "#/paths/" + escapePointer(path)
+
+		for method, op := range itemNode {
+
+			if !openAPIMethods[method] {
+				continue
+			}
+
+			opNode, _ := op.(map[string]interface{})
+			opPtr := itemPtr + "/" + method
+
+			operation := &openAPIOperation{responses: map[string]map[string]*jsonschema.Schema{}}
+
+			// operation parameters override path item parameters of the same name and location
+			index := map[string]int{}
+			for _, list := range []struct {
+				node interface{}
+				ptr  string
+			}{
+				{itemNode["parameters"], itemPtr + "/parameters"},
+				{opNode["parameters"], opPtr + "/parameters"},
+			} {
+				params, _ := list.node.([]interface{})
+				for i, p := range params {
+
+					node, ptr := deref(root, p, fmt.Sprintf("%s/%d", list.ptr, i))
+
+					param := &openAPIParameter{}
+					param.name, _ = node["name"].(string)
+					param.in, _ = node["in"].(string)
+					param.required, _ = node["required"].(bool)
+
+					if schema, ok := node["schema"].(map[string]interface{}); ok {
+						param.typ, _ = schema["type"].(string)
+
+						var err error
+						if param.schema, err = compile(ptr + "/schema"); err != nil {
+							return nil, err
+						}
+					}
+
+					key := param.in + " " + strings.ToLower(param.name)
+					if i, ok := index[key]; ok {
+						operation.params[i] = param
+						continue
+					}
+					index[key] = len(operation.params)
+					operation.params = append(operation.params, param)
+				}
+			}
+
+			if body, ok := opNode["requestBody"]; ok {
+
+				node, ptr := deref(root, body, opPtr+"/requestBody")
+				operation.bodyRequired, _ = node["required"].(bool)
+
+				content, _ := node["content"].(map[string]interface{})
+				if media, ok := content["application/json"].(map[string]interface{}); ok && media["schema"] != nil {
+
+					var err error
+					if operation.body, err = compile(ptr + "/content/" + escapePointer("application/json") + "/schema"); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			responses, _ := opNode["responses"].(map[string]interface{})
+			for status, response := range responses {
+
+				node, ptr := deref(root, response, opPtr+"/responses/"+status)
+
+				schemas := map[string]*jsonschema.Schema{}
+
+				content, _ := node["content"].(map[string]interface{})
+				for mediaType, m := range content {
+
+					media, _ := m.(map[string]interface{})
+					if media["schema"] == nil || !jsonMediaType(mediaType) {
+						schemas[mediaType] = nil
+						continue
+					}
+
+					var err error
+					if schemas[mediaType], err = compile(ptr + "/content/" + escapePointer(mediaType) + "/schema"); err != nil {
+						return nil, err
+					}
+				}
+
+				operation.responses[status] = schemas
+			}
+
+			operations[strings.ToUpper(method)+" "+path] = operation
+		}
+	}
+
+	return operations, nil
+}
+
+// validate middleware checking requests and, when enabled, responses of documented
+// operations against the document, expects to run after authentication
+func (o *OpenAPI) validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// the route is only resolved once the request reaches its handler
+		rctx := chi.RouteContext(r.Context())
+		match := chi.NewRouteContext()
+
+		if rctx == nil || rctx.Routes == nil || !rctx.Routes.Match(match, r.Method, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		pattern := match.RoutePattern()
+
+		operation, ok := o.operations[r.Method+" "+pattern]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if o.cfg.ValidateRequests {
+			if status, err := operation.validateRequest(w, r, match); err != nil {
+				writeProblem(w, r, status, err.Error())
+				return
+			}
+		}
+
+		if !o.cfg.ValidateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var buf bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&buf)
+
+		next.ServeHTTP(ww, r)
+
+		if err := operation.validateResponse(ww, buf.Bytes()); err != nil {
+			o.report(r, fmt.Errorf("%s %s %v", r.Method, pattern, err))
+		}
+	})
+}
+
+// validateRequest check parameters and body of r, returning the status to reject it with
+func (o *openAPIOperation) validateRequest(w http.ResponseWriter, r *http.Request, match *chi.Context) (int, error) {
+
+	query := r.URL.Query()
+
+	for _, p := range o.params {
+
+		var value string
+		var present bool
+
+		switch p.in {
+		case "path":
+			value = match.URLParam(p.name)
+			present = value != ""
+		case "query":
+			present = query.Has(p.name)
+			value = query.Get(p.name)
+		case "header":
+			value = r.Header.Get(p.name)
+			present = value != ""
+		default:
+			continue
+		}
+
+		if !present {
+			if p.required {
+				return http.StatusBadRequest, fmt.Errorf("missing %s parameter %s", p.in, p.name)
+			}
+			continue
+		}
+
+		v, err := p.decode(value)
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("invalid %s parameter %s: %v", p.in, p.name, err)
+		}
+
+		if p.schema == nil {
+			continue
+		}
+
+		if err := p.schema.Validate(v); err != nil {
+			return http.StatusBadRequest, fmt.Errorf("invalid %s parameter %s: %s", p.in, p.name, validationMessage(err))
+		}
+	}
+
+	if o.body == nil {
+		return 0, nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValidatedBodySize))
+	if err != nil {
+		return http.StatusRequestEntityTooLarge, errors.New("request body too large")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if o.bodyRequired {
+			return http.StatusBadRequest, errors.New("missing request body")
+		}
+		return 0, nil
+	}
+
+	v, err := decodeJSON(body)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("invalid request body: malformed json")
+	}
+
+	if err := o.body.Validate(v); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid request body: %s", validationMessage(err))
+	}
+
+	return 0, nil
+}
+
+// validateResponse check status, content type and body of a written response
+func (o *openAPIOperation) validateResponse(ww middleware.WrapResponseWriter, body []byte) error {
+
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// server errors are not part of the contract
+	if status >= http.StatusInternalServerError {
+		return nil
+	}
+
+	schemas, ok := o.responses[strconv.Itoa(status)]
+	if !ok {
+		schemas, ok = o.responses[fmt.Sprintf("%dXX", status/100)]
+	}
+	if !ok {
+		schemas, ok = o.responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("undocumented status %d", status)
+	}
+
+	if len(schemas) == 0 {
+		return nil
+	}
+
+	// mirror the content type sniffed by net/http when the handler did not set one
+	contentType := ww.Header().Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q of status %d", contentType, status)
+	}
+
+	schema, ok := schemas[mediaType]
+	if !ok {
+		return fmt.Errorf("undocumented content type %s of status %d", mediaType, status)
+	}
+
+	if schema == nil {
+		return nil
+	}
+
+	v, err := decodeJSON(body)
+	if err != nil {
+		return fmt.Errorf("malformed json body of status %d", status)
+	}
+
+	if err := schema.Validate(v); err != nil {
+		return fmt.Errorf("invalid body of status %d: %s", status, validationMessage(err))
+	}
+
+	return nil
+}
+
+// decode convert raw parameter value to the json type of its schema
+func (p *openAPIParameter) decode(value string) (interface{}, error) {
+
+	switch p.typ {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, errors.New("expected integer")
+		}
+		return json.Number(value), nil
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, errors.New("expected number")
+		}
+		return json.Number(value), nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("expected boolean")
+		}
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+// decodeJSON decode body keeping numbers exact for validation
+func decodeJSON(body []byte) (interface{}, error) {
+
+	var v interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// validationMessage most specific cause of a schema validation error prefixed with the
+// location of the offending value
+func validationMessage(err error) string {
+
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err.Error()
+	}
+
+	for len(verr.Causes) > 0 {
+		verr = verr.Causes[0]
+	}
+
+	if verr.InstanceLocation == "" {
+		return verr.Message
+	}
+
+	return verr.InstanceLocation + " " + verr.Message
+}
+
+// deref node located at ptr, following local references
+func deref(root map[string]interface{}, node interface{}, ptr string) (map[string]interface{}, string) {
+
+	m, _ := node.(map[string]interface{})
+
+	ref, ok := m["$ref"].(string)
+	if !ok || !strings.HasPrefix(ref, "#/") {
+		return m, ptr
+	}
+
+	var target interface{} = root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		parent, _ := target.(map[string]interface{})
+		target = parent[strings.NewReplacer("~1", "/", "~0", "~").Replace(token)]
+	}
+
+	return deref(root, target, ref)
+}
+
+// escapePointer escape token of a json pointer
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// jsonMediaType media types with json encoded bodies
+func jsonMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,30 @@
+package port
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// problem RFC 7807 problem details document
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// writeProblem reply with problem details document for status
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(&problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+}
@@ -0,0 +1,157 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/logging"
+	"github.com/trevatk/go-template/internal/ratelimit"
+)
+
+const (
+	// RateLimitRead route group of read only endpoints
+	RateLimitRead = "read"
+	// RateLimitWrite route group of endpoints modifying persons
+	RateLimitWrite = "write"
+	// RateLimitAdmin route group of administrative endpoints
+	RateLimitAdmin = "admin"
+	// RateLimitIP requests of each remote address, applied before authentication
+	RateLimitIP = "ip"
+)
+
+// RateLimitConfig limit of each route group, formatted as requests/period e.g. 60/1m
+type RateLimitConfig struct {
+	Read  string `yaml:"read" env:"RATE_LIMIT_READ"`
+	Write string `yaml:"write" env:"RATE_LIMIT_WRITE"`
+	Admin string `yaml:"admin" env:"RATE_LIMIT_ADMIN"`
+	IP    string `yaml:"ip" env:"RATE_LIMIT_IP"`
+}
+
+// DefaultRateLimitConfig default limit of each route group
+func DefaultRateLimitConfig() *RateLimitConfig {
+	return &RateLimitConfig{
+		Read:  "300/1m",
+		Write: "60/1m",
+		Admin: "30/1m",
+		IP:    "600/1m",
+	}
+}
+
+// Limits parse limit of each route group
+func (c *RateLimitConfig) Limits() (map[string]ratelimit.Limit, error) {
+
+	groups := map[string]string{
+		RateLimitRead:  c.Read,
+		RateLimitWrite: c.Write,
+		RateLimitAdmin: c.Admin,
+		RateLimitIP:    c.IP,
+	}
+
+	limits := make(map[string]ratelimit.Limit, len(groups))
+
+	var errs []error
+	for group, value := range groups {
+
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s rate limit %v", group, err))
+			continue
+		}
+
+		limits[group] = limit
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+// Validate check configuration values
+func (c *RateLimitConfig) Validate() error {
+	_, err := c.Limits()
+	return err
+}
+
+// NewRateLimiter create new rate limiter for the http route groups and grpc methods
+func NewRateLimiter(store ratelimit.Store, cfg *RateLimitConfig) (*ratelimit.Limiter, error) {
+
+	limits, err := cfg.Limits()
+	if err != nil {
+		return nil, err
+	}
+
+	return ratelimit.NewLimiter(store, limits), nil
+}
+
+// takeToken take a token of group for key, limited reports whether a limit applies to the
+// group. Store failures fail open, an unavailable store must not take the api down.
+func takeToken(ctx context.Context, limiter *ratelimit.Limiter, group, key string) (ratelimit.Result, bool) {
+
+	result, limited, err := limiter.Take(ctx, group, key)
+	if err != nil {
+		logging.FromContext(ctx).Sugar().Errorf("unable to apply rate limit %v", err)
+		return ratelimit.Result{}, false
+	}
+
+	return result, limited
+}
+
+// rateLimit middleware limiting requests of each client within route group
+func rateLimit(limiter *ratelimit.Limiter, group string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			result, limited := takeToken(r.Context(), limiter, group, clientKey(r))
+			if !limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				writeProblem(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey identify client by principal when authenticated otherwise by remote ip, e.g.
+// when limiting before authentication
+func clientKey(r *http.Request) string {
+
+	if principal, ok := domain.PrincipalFromContext(r.Context()); ok {
+		return principalKey(principal)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// principalKey identify client by authentication method and subject
+func principalKey(principal *domain.Principal) string {
+	return principal.Method + ":" + principal.Subject
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
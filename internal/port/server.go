@@ -0,0 +1,182 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/trevatk/go-template/internal/health"
+	"github.com/trevatk/go-template/internal/lifecycle"
+)
+
+// Server http server with ordered graceful shutdown
+type Server struct {
+	srv          *http.Server
+	log          *zap.SugaredLogger
+	health       *health.Registry
+	workers      *lifecycle.Workers
+	drainTimeout time.Duration
+}
+
+// ServerConfig http server configuration
+type ServerConfig struct {
+	// Port tcp port the server listens on
+	Port int `yaml:"port" env:"HTTP_SERVER_PORT"`
+	// ReadTimeout maximum duration for reading an entire request
+	ReadTimeout time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	// WriteTimeout maximum duration before timing out writes of the response
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	// IdleTimeout maximum time to wait for the next request on keep-alive connections
+	IdleTimeout time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	// DrainTimeout time given to in-flight requests to complete on shutdown
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"HTTP_DRAIN_TIMEOUT"`
+	// TLS optional tls termination, plaintext when no certificate is configured
+	TLS TLSConfig `yaml:"tls"`
+}
+
+// DefaultServerConfig http server defaults
+func DefaultServerConfig() *ServerConfig {
+	return &ServerConfig{
+		Port:         8080,
+		ReadTimeout:  time.Second * 15,
+		WriteTimeout: time.Second * 15,
+		IdleTimeout:  time.Second * 15,
+		DrainTimeout: time.Second * 10,
+		TLS:          *DefaultTLSConfig(),
+	}
+}
+
+// Validate check configuration values
+func (c *ServerConfig) Validate() error {
+
+	var errs []error
+
+	if c.Port < 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid port %d", c.Port))
+	}
+
+	if c.ReadTimeout <= 0 || c.WriteTimeout <= 0 || c.IdleTimeout <= 0 {
+		errs = append(errs, errors.New("timeouts must be positive"))
+	}
+
+	if c.DrainTimeout <= 0 {
+		errs = append(errs, errors.New("drain timeout must be positive"))
+	}
+
+	errs = append(errs, c.TLS.Validate())
+
+	return errors.Join(errs...)
+}
+
+// NewServer create new http server instance, in-flight requests are given the
+// configured drain timeout to complete on shutdown
+func NewServer(
+	logger *zap.Logger,
+	handler http.Handler,
+	healthRegistry *health.Registry,
+	workers *lifecycle.Workers,
+	cfg *ServerConfig,
+) (*Server, error) {
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := NewTLSConfig(logger, &cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	// tls handshake failures and other connection errors
+	errorLog, err := zap.NewStdLogAt(logger.Named("http server"), zap.WarnLevel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create error log %v", err)
+	}
+
+	return &Server{
+		srv: &http.Server{
+			Addr:         ":" + strconv.Itoa(cfg.Port),
+			Handler:      handler,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			IdleTimeout:  cfg.IdleTimeout,
+			TLSConfig:    tlsConfig,
+			ErrorLog:     errorLog,
+		},
+		log:          logger.Named("http server").Sugar(),
+		health:       healthRegistry,
+		workers:      workers,
+		drainTimeout: cfg.DrainTimeout,
+	}, nil
+}
+
+// Listen bind configured address, separate from Serve so bind failures such as a port
+// already in use surface before the service reports started
+func (s *Server) Listen() (net.Listener, error) {
+
+	l, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s %v", s.srv.Addr, err)
+	}
+
+	return l, nil
+}
+
+// Serve accept connections on l until shutdown, a regular shutdown is not an error.
+// Connections are tls terminated when tls is configured.
+func (s *Server) Serve(l net.Listener) error {
+
+	var err error
+	if s.srv.TLSConfig != nil {
+		err = s.srv.ServeTLS(l, "", "")
+	} else {
+		err = s.srv.Serve(l)
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown fail readiness, stop accepting connections, drain in-flight requests and wait
+// for background workers. Connections still open after the drain timeout are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+
+	// normally already failing, the application fails readiness before any listener stops
+	s.health.Drain()
+
+	s.log.Infof("drain in-flight requests, timeout %s", s.drainTimeout)
+
+	drainCtx, cancel := context.WithTimeout(ctx, s.drainTimeout)
+	defer cancel()
+
+	var errs []error
+
+	err := s.srv.Shutdown(drainCtx)
+	if err != nil {
+
+		errs = append(errs, fmt.Errorf("failed to drain http server %v", err))
+
+		// drain timed out, terminate remaining connections
+		if err := s.srv.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close http server %v", err))
+		}
+	}
+
+	s.log.Info("wait for background workers")
+
+	err = s.workers.Wait(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to wait for background workers %v", err))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package port
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/trevatk/go-template/internal/health"
+	"github.com/trevatk/go-template/internal/lifecycle"
+)
+
+func TestServerShutdown(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cfg := DefaultServerConfig()
+	cfg.DrainTimeout = time.Second * 5
+
+	started := make(chan struct{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(time.Millisecond * 300)
+		_, _ = w.Write([]byte("slow"))
+	})
+
+	healthRegistry := health.NewRegistry(health.RegistryParams{})
+	workers := lifecycle.NewWorkers()
+
+	server, err := NewServer(zap.NewNop(), handler, healthRegistry, workers, cfg)
+	assert.NoError(err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(l) }()
+
+	// background worker finishing its work once shutdown is signalled
+	var workerDone atomic.Bool
+	workers.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(time.Millisecond * 50)
+		workerDone.Store(true)
+	})
+
+	type response struct {
+		body string
+		err  error
+	}
+
+	responses := make(chan response, 1)
+	go func() {
+
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			responses <- response{err: err}
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		b, err := io.ReadAll(resp.Body)
+		responses <- response{body: string(b), err: err}
+	}()
+
+	<-started
+
+	assert.Equal(health.StatusOK, healthRegistry.Run(context.Background(), health.Readiness).Status)
+
+	assert.NoError(server.Shutdown(context.Background()))
+
+	// slow request completed during shutdown
+	r := <-responses
+	assert.NoError(r.err)
+	assert.Equal("slow", r.body)
+
+	assert.True(workerDone.Load())
+	assert.NoError(<-served)
+
+	// readiness fails and new connections are refused
+	report := healthRegistry.Run(context.Background(), health.Readiness)
+	assert.Equal(health.StatusFail, report.Status)
+	assert.Equal(health.ErrShuttingDown.Error(), report.Checks["shutdown"].Error)
+
+	_, err = http.Get("http://" + l.Addr().String() + "/slow")
+	assert.Error(err)
+}
+
+func TestServerListen(t *testing.T) {
+
+	assert := assert.New(t)
+
+	// occupy port
+	l, err := net.Listen("tcp", ":0")
+	assert.NoError(err)
+	defer func() { _ = l.Close() }()
+
+	cfg := DefaultServerConfig()
+	cfg.Port = l.Addr().(*net.TCPAddr).Port
+
+	server, err := NewServer(zap.NewNop(), http.NotFoundHandler(), health.NewRegistry(health.RegistryParams{}), lifecycle.NewWorkers(), cfg)
+	assert.NoError(err)
+
+	// bind failure is reported synchronously
+	_, err = server.Listen()
+	assert.ErrorContains(err, "failed to listen")
+}
@@ -0,0 +1,270 @@
+package port
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// ClientAuthNone client certificates are not requested
+	ClientAuthNone = "none"
+	// ClientAuthOptional client certificates are verified when presented
+	ClientAuthOptional = "optional"
+	// ClientAuthRequire every client must present a valid certificate
+	ClientAuthRequire = "require"
+)
+
+// reloadInterval minimum time between checks of the certificate files for changes
+var reloadInterval = time.Second * 5
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig http server tls configuration, tls is enabled when a certificate is configured
+type TLSConfig struct {
+	// CertFile pem encoded server certificate chain, reloaded on change
+	CertFile string `yaml:"cert_file" env:"HTTP_TLS_CERT_FILE"`
+	// KeyFile pem encoded private key of the server certificate, reloaded on change
+	KeyFile string `yaml:"key_file" env:"HTTP_TLS_KEY_FILE"`
+	// MinVersion minimum accepted protocol version, 1.2 or 1.3
+	MinVersion string `yaml:"min_version" env:"HTTP_TLS_MIN_VERSION"`
+	// CipherSuites accepted tls 1.2 cipher suites by name, go defaults when empty
+	CipherSuites []string `yaml:"cipher_suites" env:"HTTP_TLS_CIPHER_SUITES"`
+	// ClientCAFile pem encoded ca bundle client certificates are verified against, reloaded on change
+	ClientCAFile string `yaml:"client_ca_file" env:"HTTP_TLS_CLIENT_CA_FILE"`
+	// ClientAuth client certificate policy, none, optional or require
+	ClientAuth string `yaml:"client_auth" env:"HTTP_TLS_CLIENT_AUTH"`
+}
+
+// DefaultTLSConfig tls defaults, disabled until a certificate is configured
+func DefaultTLSConfig() *TLSConfig {
+	return &TLSConfig{
+		MinVersion:   "1.2",
+		CipherSuites: []string{},
+		ClientAuth:   ClientAuthNone,
+	}
+}
+
+// Enabled check if tls is configured
+func (c *TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Validate check configuration values
+func (c *TLSConfig) Validate() error {
+
+	var errs []error
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		errs = append(errs, errors.New("tls cert and key file must be configured together"))
+	}
+
+	if _, ok := tlsVersions[c.MinVersion]; !ok {
+		errs = append(errs, fmt.Errorf("invalid tls min version %q, expected 1.2 or 1.3", c.MinVersion))
+	}
+
+	if _, err := cipherSuites(c.CipherSuites); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch c.ClientAuth {
+	case ClientAuthNone:
+	case ClientAuthOptional, ClientAuthRequire:
+		if c.ClientCAFile == "" {
+			errs = append(errs, fmt.Errorf("client auth %s requires a client ca file", c.ClientAuth))
+		}
+		if !c.Enabled() {
+			errs = append(errs, fmt.Errorf("client auth %s requires tls", c.ClientAuth))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid client auth %q, expected none, optional or require", c.ClientAuth))
+	}
+
+	return errors.Join(errs...)
+}
+
+// cipherSuites resolve cipher suite names, only secure suites are accepted
+func cipherSuites(names []string) ([]uint16, error) {
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := map[string]uint16{}
+	for _, s := range tls.CipherSuites() {
+		known[s.Name] = s.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// NewTLSConfig create server tls configuration whose certificate and client ca bundle
+// are reloaded when their files change, returns nil if tls is disabled
+func NewTLSConfig(logger *zap.Logger, cfg *TLSConfig) (*tls.Config, error) {
+
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	suites, _ := cipherSuites(cfg.CipherSuites)
+
+	base := &tls.Config{
+		MinVersion:   tlsVersions[cfg.MinVersion],
+		CipherSuites: suites,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	switch cfg.ClientAuth {
+	case ClientAuthOptional:
+		base.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	store := &certStore{
+		cfg: cfg,
+		log: logger.Named("tls").Sugar(),
+	}
+
+	// fail on startup rather than on the first handshake
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion: base.MinVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+
+			cert, pool := store.current()
+
+			c := base.Clone()
+			c.Certificates = []tls.Certificate{*cert}
+			c.ClientCAs = pool
+
+			return c, nil
+		},
+	}, nil
+}
+
+// certStore server certificate and client ca bundle, reloaded when file modification times change
+type certStore struct {
+	cfg *TLSConfig
+	log *zap.SugaredLogger
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime map[string]time.Time
+	checked time.Time
+}
+
+// current certificate and ca pool, files are checked for changes at most every reload interval.
+// A failed reload keeps serving the previous certificate.
+func (s *certStore) current() (*tls.Certificate, *x509.CertPool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.checked) >= reloadInterval && s.changed() {
+		if err := s.loadLocked(); err != nil {
+			s.log.Errorf("keep previous certificate %v", err)
+		} else {
+			s.log.Info("reloaded tls certificate")
+		}
+	}
+
+	return s.cert, s.pool
+}
+
+func (s *certStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.loadLocked()
+}
+
+func (s *certStore) files() []string {
+
+	files := []string{s.cfg.CertFile, s.cfg.KeyFile}
+	if s.cfg.ClientCAFile != "" {
+		files = append(files, s.cfg.ClientCAFile)
+	}
+
+	return files
+}
+
+func (s *certStore) changed() bool {
+
+	s.checked = time.Now()
+
+	for _, f := range s.files() {
+		info, err := os.Stat(f)
+		if err != nil || !info.ModTime().Equal(s.modTime[f]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *certStore) loadLocked() error {
+
+	s.checked = time.Now()
+
+	modTime := map[string]time.Time{}
+	for _, f := range s.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("failed to stat tls file %v", err)
+		}
+		modTime[f] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.cfg.CertFile, s.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load tls certificate %v", err)
+	}
+
+	var pool *x509.CertPool
+	if s.cfg.ClientCAFile != "" {
+
+		b, err := os.ReadFile(s.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client ca file %v", err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("client ca file %s contains no certificates", s.cfg.ClientCAFile)
+		}
+	}
+
+	s.cert = &cert
+	s.pool = pool
+	s.modTime = modTime
+
+	return nil
+}
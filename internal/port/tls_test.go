@@ -0,0 +1,233 @@
+package port
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/trevatk/go-template/internal/auth"
+	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/health"
+	"github.com/trevatk/go-template/internal/lifecycle"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issue certificate for subject signed by parent, self signed ca if parent is nil
+func issue(t *testing.T, subject pkix.Name, parent *testCert) *testCert {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return &testCert{cert: cert, key: key}
+}
+
+// write pem encoded certificate and key into dir
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+
+	b, err := x509.MarshalECPrivateKey(c.key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), 0600))
+
+	return certFile, keyFile
+}
+
+func (c *testCert) tls() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func TestTLSConfigValidate(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.NoError(DefaultTLSConfig().Validate())
+
+	cfg := DefaultTLSConfig()
+	cfg.CertFile = "server.crt"
+	cfg.MinVersion = "1.0"
+	cfg.CipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"}
+	cfg.ClientAuth = ClientAuthRequire
+
+	err := cfg.Validate()
+	assert.ErrorContains(err, "cert and key file must be configured together")
+	assert.ErrorContains(err, `invalid tls min version "1.0"`)
+	assert.ErrorContains(err, `insecure cipher suite "TLS_RSA_WITH_RC4_128_SHA"`)
+	assert.ErrorContains(err, "client auth require requires a client ca file")
+}
+
+func TestServerMutualTLS(t *testing.T) {
+
+	assert := assert.New(t)
+
+	reloadInterval = 0
+	defer func() { reloadInterval = time.Second * 5 }()
+
+	dir := t.TempDir()
+
+	ca := issue(t, pkix.Name{CommonName: "test ca"}, nil)
+	caFile, _ := ca.write(t, dir, "ca")
+
+	server := issue(t, pkix.Name{CommonName: "server"}, ca)
+	certFile, keyFile := server.write(t, dir, "server")
+
+	client := issue(t, pkix.Name{CommonName: "billing", Organization: []string{testTenant}, OrganizationalUnit: []string{auth.RoleReader}}, ca)
+
+	cfg := DefaultServerConfig()
+	cfg.TLS.CertFile = certFile
+	cfg.TLS.KeyFile = keyFile
+	cfg.TLS.MinVersion = "1.3"
+	cfg.TLS.ClientCAFile = caFile
+	cfg.TLS.ClientAuth = ClientAuthRequire
+
+	handler := authenticate(auth.NewCertificateAuthenticator())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := domain.PrincipalFromContext(r.Context())
+		_ = json.NewEncoder(w).Encode(principal)
+	}))
+
+	s, err := NewServer(zap.NewNop(), handler, health.NewRegistry(health.RegistryParams{}), lifecycle.NewWorkers(), cfg)
+	assert.NoError(err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+
+	go func() { _ = s.Serve(l) }()
+	defer func() { _ = s.srv.Close() }()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	get := func(certs ...tls.Certificate) (*http.Response, error) {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		return c.Get("https://" + l.Addr().String() + "/")
+	}
+
+	// subject mapped onto principal
+	resp, err := get(client.tls())
+	assert.NoError(err)
+
+	var principal domain.Principal
+	assert.NoError(json.NewDecoder(resp.Body).Decode(&principal))
+	_ = resp.Body.Close()
+
+	assert.Equal("cert:billing", principal.Subject)
+	assert.Equal(testTenant, principal.TenantID)
+	assert.Equal([]string{auth.RoleReader}, principal.Roles)
+	assert.Equal(auth.MethodCertificate, principal.Method)
+	assert.Equal("server", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	// client certificate is required
+	_, err = get()
+	assert.Error(err)
+
+	// certificates of other authorities are rejected
+	other := issue(t, pkix.Name{CommonName: "intruder"}, issue(t, pkix.Name{CommonName: "other ca"}, nil))
+	_, err = get(other.tls())
+	assert.Error(err)
+
+	// rotated server certificate is served without restart
+	rotated := issue(t, pkix.Name{CommonName: "rotated"}, ca)
+	rotated.write(t, dir, "server")
+
+	// ensure modification time differs on coarse grained file systems
+	later := time.Now().Add(time.Second)
+	assert.NoError(os.Chtimes(certFile, later, later))
+	assert.NoError(os.Chtimes(keyFile, later, later))
+
+	resp, err = get(client.tls())
+	assert.NoError(err)
+	_ = resp.Body.Close()
+
+	assert.Equal("rotated", resp.TLS.PeerCertificates[0].Subject.CommonName)
+}
+
+func TestAdminServerTLS(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cfg := DefaultAdminConfig()
+	cfg.LocalOnly = false
+
+	// plaintext beyond localhost is refused
+	_, err := NewAdminServer(zap.NewNop(), http.NotFoundHandler(), cfg, DefaultServerConfig())
+	assert.ErrorContains(err, "admin listener must be local only unless http tls is configured")
+
+	dir := t.TempDir()
+
+	ca := issue(t, pkix.Name{CommonName: "test ca"}, nil)
+	certFile, keyFile := issue(t, pkix.Name{CommonName: "admin"}, ca).write(t, dir, "server")
+
+	serverCfg := DefaultServerConfig()
+	serverCfg.TLS.CertFile = certFile
+	serverCfg.TLS.KeyFile = keyFile
+
+	s, err := NewAdminServer(zap.NewNop(), http.NotFoundHandler(), cfg, serverCfg)
+	assert.NoError(err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+
+	go func() { _ = s.Serve(l) }()
+	defer func() { _ = s.srv.Close() }()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	resp, err := c.Get("https://" + l.Addr().String() + "/")
+	assert.NoError(err)
+	_ = resp.Body.Close()
+
+	assert.Equal("admin", resp.TLS.PeerCertificates[0].Subject.CommonName)
+}
@@ -0,0 +1,63 @@
+package port
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/trevatk/go-template/internal/logging"
+)
+
+// traceRequest middleware starting server span continuing w3c trace context of the caller,
+// span is named after the matched route pattern once routing completed
+func traceRequest(tp trace.TracerProvider) func(http.Handler) http.Handler {
+
+	tracer := tp.Tracer("github.com/trevatk/go-template/internal/port")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(r.Method),
+					semconv.HTTPTarget(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			// correlate log lines with the trace
+			if sc := span.SpanContext(); sc.IsValid() {
+				ctx = logging.WithContext(ctx, logging.FromContext(ctx).With(zap.String("trace_id", sc.TraceID().String())))
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if route := routePattern(r); route != "" {
+				span.SetName(r.Method + " " + route)
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+
+			span.SetAttributes(semconv.HTTPStatusCode(status))
+
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
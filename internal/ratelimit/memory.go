@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// sweepInterval number of takes between removal of refilled buckets
+	sweepInterval = 1024
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+// MemoryStore in process bucket store, buckets are not shared between instances
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+	now     func() time.Time
+}
+
+// NewMemoryStore create new in memory store instance
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Take remove one token from bucket identified by key
+func (ms *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := ms.now()
+	rate := limit.rate()
+	capacity := float64(limit.burst())
+
+	ms.takes++
+	if ms.takes%sweepInterval == 0 {
+		ms.sweep(now)
+	}
+
+	b, ok := ms.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		ms.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := Result{Limit: limit.burst()}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	b.full = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// sweep drop buckets which have refilled, they are equivalent to new buckets
+func (ms *MemoryStore) sweep(now time.Time) {
+	for key, b := range ms.buckets {
+		if !now.Before(b.full) {
+			delete(ms.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
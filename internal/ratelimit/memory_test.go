@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Unix(0, 0)
+
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Requests: 2, Period: time.Second * 10}
+
+	result, err := store.Take(context.TODO(), "client", limit)
+	assert.NoError(err)
+	assert.True(result.Allowed)
+	assert.Equal(2, result.Limit)
+	assert.Equal(1, result.Remaining)
+
+	result, err = store.Take(context.TODO(), "client", limit)
+	assert.NoError(err)
+	assert.True(result.Allowed)
+	assert.Equal(0, result.Remaining)
+	assert.Equal(time.Second*10, result.Reset)
+
+	result, err = store.Take(context.TODO(), "client", limit)
+	assert.NoError(err)
+	assert.False(result.Allowed)
+	assert.Equal(time.Second*5, result.RetryAfter)
+
+	// other keys have their own bucket
+	result, err = store.Take(context.TODO(), "other", limit)
+	assert.NoError(err)
+	assert.True(result.Allowed)
+
+	// one token refilled after rate interval
+	now = now.Add(time.Second * 5)
+
+	result, err = store.Take(context.TODO(), "client", limit)
+	assert.NoError(err)
+	assert.True(result.Allowed)
+	assert.Equal(0, result.Remaining)
+}
+
+func TestParseLimit(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cases := []struct {
+		input    string
+		expected Limit
+		valid    bool
+	}{
+		{input: "100/1m", expected: Limit{Requests: 100, Period: time.Minute, Burst: 100}, valid: true},
+		{input: "10/s", expected: Limit{Requests: 10, Period: time.Second, Burst: 10}, valid: true},
+		{input: "10", valid: false},
+		{input: "0/1m", valid: false},
+		{input: "10/forever", valid: false},
+	}
+
+	for _, c := range cases {
+
+		limit, err := ParseLimit(c.input)
+		if !c.valid {
+			assert.Error(err)
+			continue
+		}
+
+		assert.NoError(err)
+		assert.Equal(c.expected, limit)
+	}
+}
@@ -0,0 +1,135 @@
+// Package ratelimit token bucket rate limiting
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit token bucket refilling Requests tokens every Period, holding at most Burst tokens
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// ParseLimit parse limit expressed as <requests>/<period>, e.g. 100/1m or 10/s
+func ParseLimit(s string) (Limit, error) {
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q expected <requests>/<period>", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit requests %q", requests)
+	}
+
+	// allow unit shorthand such as 10/s
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit period %q", period)
+	}
+
+	return Limit{Requests: n, Period: d, Burst: n}, nil
+}
+
+// String format limit as <requests>/<period>
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// rate tokens refilled per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// Result outcome of taking a token from a bucket
+type Result struct {
+	Allowed bool
+	// Limit bucket capacity
+	Limit int
+	// Remaining tokens left in bucket
+	Remaining int
+	// Reset time until bucket is full again
+	Reset time.Duration
+	// RetryAfter time until next token is available when not allowed
+	RetryAfter time.Duration
+}
+
+// Store bucket storage, implementations may share buckets across instances
+type Store interface {
+	// Take remove one token from bucket identified by key
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limiter rate limiter with limits configured per route group
+type Limiter struct {
+	store Store
+
+	mu     sync.RWMutex
+	limits map[string]Limit
+}
+
+// NewLimiter create new limiter instance
+func NewLimiter(store Store, limits map[string]Limit) *Limiter {
+	l := &Limiter{store: store}
+	l.SetLimits(limits)
+	return l
+}
+
+// SetLimits replace limits of all route groups
+func (l *Limiter) SetLimits(limits map[string]Limit) {
+
+	copied := make(map[string]Limit, len(limits))
+	for group, limit := range limits {
+		copied[group] = limit
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limits = copied
+}
+
+// Limit retrieve limit of route group, false if group is not limited
+func (l *Limiter) Limit(group string) (Limit, bool) {
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	limit, ok := l.limits[group]
+	return limit, ok
+}
+
+// Take remove one token from bucket of client key within route group
+func (l *Limiter) Take(ctx context.Context, group, key string) (Result, bool, error) {
+
+	limit, ok := l.Limit(group)
+	if !ok {
+		return Result{}, false, nil
+	}
+
+	result, err := l.store.Take(ctx, group+"|"+key, limit)
+	if err != nil {
+		return Result{}, true, fmt.Errorf("failed to take rate limit token %v", err)
+	}
+
+	return result, true, nil
+}
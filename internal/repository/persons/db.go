@@ -24,15 +24,39 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.anonymizePersonHistoryStmt, err = db.PrepareContext(ctx, anonymizePersonHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query AnonymizePersonHistory: %w", err)
+	}
 	if q.deletePersonStmt, err = db.PrepareContext(ctx, deletePerson); err != nil {
 		return nil, fmt.Errorf("error preparing query DeletePerson: %w", err)
 	}
 	if q.insertPersonStmt, err = db.PrepareContext(ctx, insertPerson); err != nil {
 		return nil, fmt.Errorf("error preparing query InsertPerson: %w", err)
 	}
+	if q.insertPersonHistoryStmt, err = db.PrepareContext(ctx, insertPersonHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query InsertPersonHistory: %w", err)
+	}
+	if q.listPersonHistoryStmt, err = db.PrepareContext(ctx, listPersonHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query ListPersonHistory: %w", err)
+	}
+	if q.listPersonsStmt, err = db.PrepareContext(ctx, listPersons); err != nil {
+		return nil, fmt.Errorf("error preparing query ListPersons: %w", err)
+	}
+	if q.listPersonsHistoryStmt, err = db.PrepareContext(ctx, listPersonsHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query ListPersonsHistory: %w", err)
+	}
 	if q.readPersonStmt, err = db.PrepareContext(ctx, readPerson); err != nil {
 		return nil, fmt.Errorf("error preparing query ReadPerson: %w", err)
 	}
+	if q.readPersonsStmt, err = db.PrepareContext(ctx, readPersons); err != nil {
+		return nil, fmt.Errorf("error preparing query ReadPersons: %w", err)
+	}
+	if q.searchPersonsStmt, err = db.PrepareContext(ctx, searchPersons); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchPersons: %w", err)
+	}
+	if q.transferPersonStmt, err = db.PrepareContext(ctx, transferPerson); err != nil {
+		return nil, fmt.Errorf("error preparing query TransferPerson: %w", err)
+	}
 	if q.updatePersonStmt, err = db.PrepareContext(ctx, updatePerson); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdatePerson: %w", err)
 	}
@@ -41,6 +65,11 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 
 func (q *Queries) Close() error {
 	var err error
+	if q.anonymizePersonHistoryStmt != nil {
+		if cerr := q.anonymizePersonHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing anonymizePersonHistoryStmt: %w", cerr)
+		}
+	}
 	if q.deletePersonStmt != nil {
 		if cerr := q.deletePersonStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deletePersonStmt: %w", cerr)
@@ -51,11 +80,46 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing insertPersonStmt: %w", cerr)
 		}
 	}
+	if q.insertPersonHistoryStmt != nil {
+		if cerr := q.insertPersonHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing insertPersonHistoryStmt: %w", cerr)
+		}
+	}
+	if q.listPersonHistoryStmt != nil {
+		if cerr := q.listPersonHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listPersonHistoryStmt: %w", cerr)
+		}
+	}
+	if q.listPersonsStmt != nil {
+		if cerr := q.listPersonsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listPersonsStmt: %w", cerr)
+		}
+	}
+	if q.listPersonsHistoryStmt != nil {
+		if cerr := q.listPersonsHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listPersonsHistoryStmt: %w", cerr)
+		}
+	}
 	if q.readPersonStmt != nil {
 		if cerr := q.readPersonStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing readPersonStmt: %w", cerr)
 		}
 	}
+	if q.readPersonsStmt != nil {
+		if cerr := q.readPersonsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing readPersonsStmt: %w", cerr)
+		}
+	}
+	if q.searchPersonsStmt != nil {
+		if cerr := q.searchPersonsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchPersonsStmt: %w", cerr)
+		}
+	}
+	if q.transferPersonStmt != nil {
+		if cerr := q.transferPersonStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing transferPersonStmt: %w", cerr)
+		}
+	}
 	if q.updatePersonStmt != nil {
 		if cerr := q.updatePersonStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updatePersonStmt: %w", cerr)
@@ -98,21 +162,37 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db               DBTX
-	tx               *sql.Tx
-	deletePersonStmt *sql.Stmt
-	insertPersonStmt *sql.Stmt
-	readPersonStmt   *sql.Stmt
-	updatePersonStmt *sql.Stmt
+	db                         DBTX
+	tx                         *sql.Tx
+	anonymizePersonHistoryStmt *sql.Stmt
+	deletePersonStmt           *sql.Stmt
+	insertPersonStmt           *sql.Stmt
+	insertPersonHistoryStmt    *sql.Stmt
+	listPersonHistoryStmt      *sql.Stmt
+	listPersonsStmt            *sql.Stmt
+	listPersonsHistoryStmt     *sql.Stmt
+	readPersonStmt             *sql.Stmt
+	readPersonsStmt            *sql.Stmt
+	searchPersonsStmt          *sql.Stmt
+	transferPersonStmt         *sql.Stmt
+	updatePersonStmt           *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:               tx,
-		tx:               tx,
-		deletePersonStmt: q.deletePersonStmt,
-		insertPersonStmt: q.insertPersonStmt,
-		readPersonStmt:   q.readPersonStmt,
-		updatePersonStmt: q.updatePersonStmt,
+		db:                         tx,
+		tx:                         tx,
+		anonymizePersonHistoryStmt: q.anonymizePersonHistoryStmt,
+		deletePersonStmt:           q.deletePersonStmt,
+		insertPersonStmt:           q.insertPersonStmt,
+		insertPersonHistoryStmt:    q.insertPersonHistoryStmt,
+		listPersonHistoryStmt:      q.listPersonHistoryStmt,
+		listPersonsStmt:            q.listPersonsStmt,
+		listPersonsHistoryStmt:     q.listPersonsHistoryStmt,
+		readPersonStmt:             q.readPersonStmt,
+		readPersonsStmt:            q.readPersonsStmt,
+		searchPersonsStmt:          q.searchPersonsStmt,
+		transferPersonStmt:         q.transferPersonStmt,
+		updatePersonStmt:           q.updatePersonStmt,
 	}
 }
@@ -16,4 +16,16 @@ type Person struct {
 	Email     string
 	CreatedAt time.Time
 	UpdatedAt sql.NullTime
+	TenantID  string
+	Owner     string
+}
+
+type PersonHistory struct {
+	ID        int64
+	PersonID  int64
+	Action    string
+	Snapshot  sql.NullString
+	CreatedAt time.Time
+	TenantID  string
+	Actor     string
 }
@@ -8,32 +8,46 @@ package persons
 import (
 	"context"
 	"database/sql"
+	"strings"
 )
 
 const deletePerson = `-- name: DeletePerson :execresult
-DELETE FROM persons WHERE id = ?
+DELETE FROM persons WHERE id = ? AND tenant_id = ?
 `
 
-func (q *Queries) DeletePerson(ctx context.Context, id int64) (sql.Result, error) {
-	return q.exec(ctx, q.deletePersonStmt, deletePerson, id)
+type DeletePersonParams struct {
+	ID       int64
+	TenantID string
+}
+
+func (q *Queries) DeletePerson(ctx context.Context, arg *DeletePersonParams) (sql.Result, error) {
+	return q.exec(ctx, q.deletePersonStmt, deletePerson, arg.ID, arg.TenantID)
 }
 
 const insertPerson = `-- name: InsertPerson :one
-INSERT INTO persons (fname, lname, email)
+INSERT INTO persons (fname, lname, email, tenant_id, owner)
 VALUES (
-    ?, ?, ?
-) RETURNING id, fname, lname, email, created_at, updated_at
+    ?, ?, ?, ?, ?
+) RETURNING id, fname, lname, email, created_at, updated_at, tenant_id, owner
 `
 
 type InsertPersonParams struct {
-	Fname string
-	Lname string
-	Email string
+	Fname    string
+	Lname    string
+	Email    string
+	TenantID string
+	Owner    string
 }
 
 // add person into database
 func (q *Queries) InsertPerson(ctx context.Context, arg *InsertPersonParams) (*Person, error) {
-	row := q.queryRow(ctx, q.insertPersonStmt, insertPerson, arg.Fname, arg.Lname, arg.Email)
+	row := q.queryRow(ctx, q.insertPersonStmt, insertPerson,
+		arg.Fname,
+		arg.Lname,
+		arg.Email,
+		arg.TenantID,
+		arg.Owner,
+	)
 	var i Person
 	err := row.Scan(
 		&i.ID,
@@ -42,18 +56,220 @@ func (q *Queries) InsertPerson(ctx context.Context, arg *InsertPersonParams) (*P
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TenantID,
+		&i.Owner,
 	)
 	return &i, err
 }
 
+const listPersons = `-- name: ListPersons :many
+SELECT id, fname, lname, email, created_at, updated_at, tenant_id, owner
+FROM persons
+WHERE tenant_id = ? AND id > ?
+ORDER BY id
+LIMIT ?
+`
+
+type ListPersonsParams struct {
+	TenantID string
+	ID       int64
+	Limit    int64
+}
+
+// page through tenant persons ordered by id
+func (q *Queries) ListPersons(ctx context.Context, arg *ListPersonsParams) ([]*Person, error) {
+	rows, err := q.query(ctx, q.listPersonsStmt, listPersons, arg.TenantID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Person{}
+	for rows.Next() {
+		var i Person
+		if err := rows.Scan(
+			&i.ID,
+			&i.Fname,
+			&i.Lname,
+			&i.Email,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TenantID,
+			&i.Owner,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const readPerson = `-- name: ReadPerson :one
-SELECT id, fname, lname, email, created_at, updated_at
+SELECT id, fname, lname, email, created_at, updated_at, tenant_id, owner
 FROM persons
-WHERE id = ?
+WHERE id = ? AND tenant_id = ?
 `
 
-func (q *Queries) ReadPerson(ctx context.Context, id int64) (*Person, error) {
-	row := q.queryRow(ctx, q.readPersonStmt, readPerson, id)
+type ReadPersonParams struct {
+	ID       int64
+	TenantID string
+}
+
+func (q *Queries) ReadPerson(ctx context.Context, arg *ReadPersonParams) (*Person, error) {
+	row := q.queryRow(ctx, q.readPersonStmt, readPerson, arg.ID, arg.TenantID)
+	var i Person
+	err := row.Scan(
+		&i.ID,
+		&i.Fname,
+		&i.Lname,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TenantID,
+		&i.Owner,
+	)
+	return &i, err
+}
+
+const readPersons = `-- name: ReadPersons :many
+SELECT id, fname, lname, email, created_at, updated_at, tenant_id, owner
+FROM persons
+WHERE tenant_id = ? AND id IN (/*SLICE:ids*/?)
+ORDER BY id
+`
+
+type ReadPersonsParams struct {
+	TenantID string
+	Ids      []int64
+}
+
+// batch read of tenant persons by id
+func (q *Queries) ReadPersons(ctx context.Context, arg *ReadPersonsParams) ([]*Person, error) {
+	query := readPersons
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.TenantID)
+	if len(arg.Ids) > 0 {
+		for _, v := range arg.Ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(arg.Ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.query(ctx, nil, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Person{}
+	for rows.Next() {
+		var i Person
+		if err := rows.Scan(
+			&i.ID,
+			&i.Fname,
+			&i.Lname,
+			&i.Email,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TenantID,
+			&i.Owner,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPersons = `-- name: SearchPersons :many
+SELECT id, fname, lname, email, created_at, updated_at, tenant_id, owner
+FROM persons
+WHERE tenant_id = ? AND id > ? AND (
+    fname LIKE ? ESCAPE '\' OR
+    lname LIKE ? ESCAPE '\' OR
+    email LIKE ? ESCAPE '\'
+)
+ORDER BY id
+LIMIT ?
+`
+
+type SearchPersonsParams struct {
+	TenantID string
+	ID       int64
+	Fname    string
+	Lname    string
+	Email    string
+	Limit    int64
+}
+
+// page through tenant persons matching a pattern on name or email
+func (q *Queries) SearchPersons(ctx context.Context, arg *SearchPersonsParams) ([]*Person, error) {
+	rows, err := q.query(ctx, q.searchPersonsStmt, searchPersons,
+		arg.TenantID,
+		arg.ID,
+		arg.Fname,
+		arg.Lname,
+		arg.Email,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Person{}
+	for rows.Next() {
+		var i Person
+		if err := rows.Scan(
+			&i.ID,
+			&i.Fname,
+			&i.Lname,
+			&i.Email,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TenantID,
+			&i.Owner,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const transferPerson = `-- name: TransferPerson :one
+UPDATE persons
+SET
+    owner = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND tenant_id = ?
+RETURNING id, fname, lname, email, created_at, updated_at, tenant_id, owner
+`
+
+type TransferPersonParams struct {
+	Owner    string
+	ID       int64
+	TenantID string
+}
+
+func (q *Queries) TransferPerson(ctx context.Context, arg *TransferPersonParams) (*Person, error) {
+	row := q.queryRow(ctx, q.transferPersonStmt, transferPerson, arg.Owner, arg.ID, arg.TenantID)
 	var i Person
 	err := row.Scan(
 		&i.ID,
@@ -62,6 +278,8 @@ func (q *Queries) ReadPerson(ctx context.Context, id int64) (*Person, error) {
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TenantID,
+		&i.Owner,
 	)
 	return &i, err
 }
@@ -73,15 +291,16 @@ SET
     lname = ?,
     email = ?,
     updated_at = CURRENT_TIMESTAMP
-WHERE id = ?
-RETURNING id, fname, lname, email, created_at, updated_at
+WHERE id = ? AND tenant_id = ?
+RETURNING id, fname, lname, email, created_at, updated_at, tenant_id, owner
 `
 
 type UpdatePersonParams struct {
-	Fname string
-	Lname string
-	Email string
-	ID    int64
+	Fname    string
+	Lname    string
+	Email    string
+	ID       int64
+	TenantID string
 }
 
 func (q *Queries) UpdatePerson(ctx context.Context, arg *UpdatePersonParams) (*Person, error) {
@@ -90,6 +309,7 @@ func (q *Queries) UpdatePerson(ctx context.Context, arg *UpdatePersonParams) (*P
 		arg.Lname,
 		arg.Email,
 		arg.ID,
+		arg.TenantID,
 	)
 	var i Person
 	err := row.Scan(
@@ -99,6 +319,8 @@ func (q *Queries) UpdatePerson(ctx context.Context, arg *UpdatePersonParams) (*P
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TenantID,
+		&i.Owner,
 	)
 	return &i, err
 }
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/trevatk/go-template/internal/tracing"
+
+// sqlc prefixes every generated query with its name
+var queryName = regexp.MustCompile(`^-- name: (\w+)`)
+
+// DBTX database handle accepted by the sqlc generated query packages
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+// tracedDB records a client span for every statement executed through the wrapped handle
+type tracedDB struct {
+	db     DBTX
+	tracer trace.Tracer
+}
+
+// WrapDBTX wrap connection or transaction so queries are traced using the global tracer provider
+func WrapDBTX(db DBTX) DBTX {
+	return &tracedDB{db: db, tracer: otel.Tracer(instrumentationName)}
+}
+
+func (t *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	result, err := t.db.ExecContext(ctx, query, args...)
+	recordError(span, err)
+
+	return result, err
+}
+
+func (t *tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	stmt, err := t.db.PrepareContext(ctx, query)
+	recordError(span, err)
+
+	return stmt, err
+}
+
+func (t *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	recordError(span, err)
+
+	return rows, err
+}
+
+func (t *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+
+	row := t.db.QueryRowContext(ctx, query, args...)
+	recordError(span, row.Err())
+
+	return row
+}
+
+func (t *tracedDB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+
+	name := "sql"
+	if m := queryName.FindStringSubmatch(query); m != nil {
+		name = m[1]
+	}
+
+	return t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemSqlite,
+			semconv.DBOperation(name),
+			semconv.DBStatement(query),
+		),
+	)
+}
+
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,106 @@
+// Package tracing service distributed tracing
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+)
+
+const (
+	// ExporterNone spans are created for log correlation but not exported
+	ExporterNone = "none"
+	// ExporterOTLP export spans using otlp over http, configured by the standard $OTEL_EXPORTER_OTLP_* variables
+	ExporterOTLP = "otlp"
+	// ExporterStdout write spans to stdout, intended for local testing
+	ExporterStdout = "stdout"
+
+	serviceName = "go-template"
+)
+
+// Config tracing configuration
+type Config struct {
+	// Exporter span exporter, one of none, otlp or stdout
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+}
+
+// DefaultConfig tracing defaults, spans are not exported
+func DefaultConfig() *Config {
+	return &Config{Exporter: ExporterNone}
+}
+
+// Validate check configuration values
+func (c *Config) Validate() error {
+
+	switch c.Exporter {
+	case ExporterNone, ExporterOTLP, ExporterStdout:
+		return nil
+	default:
+		return fmt.Errorf("unsupported exporter %q", c.Exporter)
+	}
+}
+
+// NewTracerProvider create tracer provider exporting to the configured exporter and install
+// it as global provider. Otlp endpoint, sampling and resource attributes follow the standard
+// $OTEL_* variables read by the sdk.
+func NewTracerProvider(lc fx.Lifecycle, cfg *Config) (trace.TracerProvider, error) {
+
+	ctx := context.Background()
+
+	var options []sdktrace.TracerProviderOption
+
+	switch cfg.Exporter {
+	case ExporterNone:
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create otlp trace exporter %v", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout trace exporter %v", err)
+		}
+		options = append(options, sdktrace.WithSyncer(exporter))
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", cfg.Exporter)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource %v", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(append(options, sdktrace.WithResource(res))...)
+
+	Install(tp)
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			// flush pending spans
+			return tp.Shutdown(ctx)
+		},
+	})
+
+	return tp, nil
+}
+
+// Install set global tracer provider and w3c trace context propagation
+func Install(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
@@ -0,0 +1,53 @@
+package tracing_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/fx/fxtest"
+
+	"github.com/trevatk/go-template/internal/tracing"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cases := []struct {
+		exporter string
+		valid    bool
+	}{
+		{
+			exporter: tracing.ExporterNone,
+			valid:    true,
+		},
+		{
+			exporter: tracing.ExporterStdout,
+			valid:    true,
+		},
+		{
+			exporter: tracing.ExporterOTLP,
+			valid:    true,
+		},
+		{
+			exporter: "jaeger",
+			valid:    false,
+		},
+	}
+
+	for _, c := range cases {
+
+		lc := fxtest.NewLifecycle(t)
+
+		tp, err := tracing.NewTracerProvider(lc, &tracing.Config{Exporter: c.exporter})
+		if !c.valid {
+			assert.Error(err)
+			continue
+		}
+
+		assert.NoError(err)
+		assert.NotNil(tp)
+
+		lc.RequireStart().RequireStop()
+	}
+}
@@ -4,67 +4,219 @@ package main
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"time"
 
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 
+	"github.com/trevatk/go-template/internal/auth"
+	"github.com/trevatk/go-template/internal/config"
 	"github.com/trevatk/go-template/internal/db"
 	"github.com/trevatk/go-template/internal/domain"
+	"github.com/trevatk/go-template/internal/features"
+	"github.com/trevatk/go-template/internal/health"
+	"github.com/trevatk/go-template/internal/lifecycle"
 	"github.com/trevatk/go-template/internal/logging"
+	"github.com/trevatk/go-template/internal/metrics"
 	"github.com/trevatk/go-template/internal/port"
+	"github.com/trevatk/go-template/internal/ratelimit"
+	"github.com/trevatk/go-template/internal/tracing"
 )
 
 func main() {
 
+	args := os.Args[1:]
+
+	// config print [flags] dumps effective configuration
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" {
+		printConfig(args[2:])
+		return
+	}
+
+	// apikey create|list|revoke [flags] [-- config flags] manages api keys
+	if len(args) >= 1 && args[0] == "apikey" {
+		apiKey(args[1:])
+		return
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatalf("invalid configuration\n%v", err)
+	}
+
 	fxApp := fx.New(
+		cfg.Provide(),
 		fx.Provide(logging.New),
+		fx.Provide(tracing.NewTracerProvider),
+		fx.Provide(auth.NewPolicy),
+		fx.Provide(func(p *auth.Policy) domain.ScopeAuthorizer { return p }),
+		fx.Provide(auth.NewJWTVerifier),
 		fx.Provide(db.NewSQLite),
 		fx.Provide(domain.NewPersonService),
+		fx.Provide(domain.NewAPIKeyService),
+		fx.Provide(domain.NewIdempotencyService),
+		fx.Provide(auth.NewAPIKeyAuthenticator),
+		fx.Provide(auth.NewCertificateAuthenticator),
 		fx.Provide(domain.NewBundle),
+		fx.Provide(fx.Annotate(ratelimit.NewMemoryStore, fx.As(new(ratelimit.Store)))),
+		fx.Provide(port.NewRateLimiter),
+		fx.Provide(metrics.NewHTTPMetrics),
+		fx.Provide(metrics.AsCollector(func(m *metrics.HTTPMetrics) *metrics.HTTPMetrics { return m })),
+		fx.Provide(metrics.NewGRPCMetrics),
+		fx.Provide(metrics.AsCollector(func(m *metrics.GRPCMetrics) *metrics.GRPCMetrics { return m })),
+		fx.Provide(metrics.AsCollector(metrics.NewDBStatsCollector)),
+		fx.Provide(metrics.AsCollector(metrics.NewMigrationCollector)),
+		fx.Provide(metrics.NewRegistry),
+		fx.Provide(health.AsCheck(health.NewDatabaseCheck)),
+		fx.Provide(health.AsCheck(health.NewMigrationCheck)),
+		fx.Provide(health.NewRegistry),
+		fx.Provide(port.NewCORS),
+		fx.Provide(features.NewFlags),
 		fx.Provide(port.NewHTTPServer),
-		fx.Provide(port.NewRouter),
+		fx.Provide(port.NewGraphQLHandler),
+		fx.Provide(port.NewOpenAPI),
+		fx.Provide(fx.Annotate(port.NewRouter, fx.As(new(http.Handler)))),
+		fx.Provide(lifecycle.NewWorkers),
+		fx.Provide(port.NewServer),
+		fx.Provide(port.NewGRPCServer),
+		fx.Provide(fx.Annotate(port.NewAdminRouter, fx.ResultTags(`name:"admin"`), fx.As(new(http.Handler)))),
+		fx.Provide(fx.Annotate(port.NewAdminServer, fx.ParamTags("", `name:"admin"`, "", ""))),
+		fx.Provide(config.AsSubscriber(config.NewLogLevelSubscriber)),
+		fx.Provide(config.AsSubscriber(config.NewRateLimitSubscriber)),
+		fx.Provide(config.AsSubscriber(config.NewFeatureSubscriber)),
+		fx.Provide(config.AsSubscriber(config.NewCORSSubscriber)),
+		fx.Invoke(registerLogger),
 		fx.Invoke(registerHooks),
+		fx.Invoke(config.NewReloader),
 	)
 
-	start, cancel := context.WithTimeout(context.TODO(), time.Second*15)
+	start, cancel := context.WithTimeout(context.TODO(), cfg.App.StartTimeout)
 	defer cancel()
 
 	if err := fxApp.Start(start); err != nil {
 		log.Fatalf("error starting service %v", err)
 	}
 
-	<-fxApp.Done()
+	signal := <-fxApp.Wait()
 
-	stop, cancel := context.WithTimeout(context.TODO(), time.Second*15)
+	stop, cancel := context.WithTimeout(context.TODO(), cfg.App.StopTimeout)
 	defer cancel()
 
 	if err := fxApp.Stop(stop); err != nil {
 		log.Fatalf("error stopping service %v", err)
 	}
-}
 
-func registerHooks(lc fx.Lifecycle, log *zap.Logger, handler http.Handler, sqlite *sql.DB) error {
+	if signal.ExitCode != 0 {
+		log.Printf("service stopped with exit code %d", signal.ExitCode)
+		cancel()
+		os.Exit(signal.ExitCode)
+	}
+}
 
-	logger := log.Named("lifecycle").Sugar()
+func printConfig(args []string) {
 
-	port := os.Getenv("HTTP_SERVER_PORT")
-	if port == "" {
-		return errors.New("$HTTP_SERVER_PORT is unset")
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatalf("invalid configuration\n%v", err)
 	}
 
-	srv := &http.Server{
-		Addr:         ":" + port,
-		Handler:      handler,
-		ReadTimeout:  time.Second * 15,
-		WriteTimeout: time.Second * 15,
-		IdleTimeout:  time.Second * 15,
+	if err := cfg.Print(os.Stdout); err != nil {
+		log.Fatalf("failed to print configuration %v", err)
 	}
+}
+
+// registerLogger install the configured logger as global logger, used by code without a request
+// scoped logger, and flush buffered entries once every other stop hook ran
+func registerLogger(lc fx.Lifecycle, log *zap.Logger) {
+
+	restore := zap.ReplaceGlobals(log)
+
+	lc.Append(
+		fx.Hook{
+			OnStop: func(ctx context.Context) error {
+
+				// stdout and stderr can not be synced on every platform, nothing left to report it to
+				_ = log.Sync()
+				restore()
+
+				return nil
+			},
+		},
+	)
+}
+
+func registerHooks(
+	lc fx.Lifecycle,
+	shutdowner fx.Shutdowner,
+	log *zap.Logger,
+	server *port.Server,
+	adminServer *port.AdminServer,
+	grpcServer *port.GRPCServer,
+	sqlite *sql.DB,
+	sqliteCfg *db.Config,
+	healthRegistry *health.Registry,
+) {
+
+	logger := log.Named("lifecycle").Sugar()
+
+	// stop hooks run in reverse order, the database is closed last so draining requests,
+	// workers and the admin listener can still use it
+	lc.Append(
+		fx.Hook{
+			OnStop: func(ctx context.Context) error {
+
+				logger.Info("close database connection")
+
+				err := sqlite.Close()
+				if err != nil {
+					logger.Errorf("failed to close database connection %v", err)
+					return err
+				}
+
+				return nil
+			},
+		},
+	)
+
+	// admin listener starts first and stops after the drain so probes and metrics are
+	// observable during startup and shutdown
+	lc.Append(
+		fx.Hook{
+			OnStart: func(ctx context.Context) error {
+
+				l, err := adminServer.Listen()
+				if err != nil {
+					return err
+				}
+
+				logger.Infof("start admin server %s", l.Addr())
+
+				go func() {
+					if err := adminServer.Serve(l); err != nil {
+						logger.Errorf("admin server failed %v", err)
+						_ = shutdowner.Shutdown(fx.ExitCode(1))
+					}
+				}()
+
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+
+				logger.Info("shutdown admin server")
+
+				err := adminServer.Shutdown(ctx)
+				if err != nil {
+					logger.Errorf("failed to shutdown admin server %v", err)
+					return err
+				}
+
+				return nil
+			},
+		},
+	)
 
 	lc.Append(
 		fx.Hook{
@@ -72,44 +224,91 @@ func registerHooks(lc fx.Lifecycle, log *zap.Logger, handler http.Handler, sqlit
 
 				logger.Info("execute database migration")
 
-				err := db.Migrate(sqlite)
+				err := db.Migrate(sqlite, sqliteCfg.MigrationsDir)
 				if err != nil {
 					return fmt.Errorf("failed to execute database migration %v", err)
 				}
 
-				logger.Infof("start http server http://localhost:%s" + port)
+				l, err := server.Listen()
+				if err != nil {
+					return err
+				}
+
+				logger.Infof("start http server %s", l.Addr())
 
 				go func() {
-					if err := srv.ListenAndServe(); err != nil {
-						logger.Fatalf("failed to start http server %v", err)
+					// serve only returns an error if the server failed, stop the application gracefully
+					if err := server.Serve(l); err != nil {
+						logger.Errorf("http server failed %v", err)
+						_ = shutdowner.Shutdown(fx.ExitCode(1))
 					}
 				}()
 
+				healthRegistry.MarkStarted()
+
 				return nil
 			},
 			OnStop: func(ctx context.Context) error {
 
-				var err error
+				logger.Info("shutdown http server")
 
-				logger.Info("close database connection")
+				err := server.Shutdown(ctx)
+				if err != nil {
+					logger.Errorf("failed to shutdown http server %v", err)
+					return err
+				}
+
+				return nil
+			},
+		},
+	)
 
-				err = sqlite.Close()
+	// grpc starts once migrations ran and stops before the http server so watch streams end before the drain
+	lc.Append(
+		fx.Hook{
+			OnStart: func(ctx context.Context) error {
+
+				l, err := grpcServer.Listen()
 				if err != nil {
-					logger.Errorf("failed to close database connection %v", err)
+					return err
 				}
 
-				logger.Info("shutdown http server")
+				logger.Infof("start grpc server %s", l.Addr())
 
-				err = srv.Close()
-				if err != nil && !errors.Is(err, http.ErrServerClosed) {
-					logger.Errorf("failed to shutdown http server %v", err)
+				go func() {
+					if err := grpcServer.Serve(l); err != nil {
+						logger.Errorf("grpc server failed %v", err)
+						_ = shutdowner.Shutdown(fx.ExitCode(1))
+					}
+				}()
+
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+
+				logger.Info("shutdown grpc server")
+
+				err := grpcServer.Shutdown(ctx)
+				if err != nil {
+					logger.Errorf("failed to shutdown grpc server %v", err)
+					return err
 				}
 
-				// redudant logging
-				return err
+				return nil
 			},
 		},
 	)
 
-	return nil
+	// appended last so it stops first, readiness fails before any listener stops
+	lc.Append(
+		fx.Hook{
+			OnStop: func(ctx context.Context) error {
+
+				logger.Info("mark readiness failing")
+				healthRegistry.Drain()
+
+				return nil
+			},
+		},
+	)
 }
@@ -0,0 +1,2 @@
+DROP INDEX person_history_person_id;
+DROP TABLE person_history;
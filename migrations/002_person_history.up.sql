@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS person_history (
+    id INTEGER PRIMARY KEY,
+    person_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    snapshot TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS person_history_person_id ON person_history (person_id);
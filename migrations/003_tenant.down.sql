@@ -0,0 +1,5 @@
+DROP INDEX person_history_tenant_id;
+DROP INDEX persons_tenant_id;
+
+ALTER TABLE person_history DROP COLUMN tenant_id;
+ALTER TABLE persons DROP COLUMN tenant_id;
@@ -0,0 +1,5 @@
+ALTER TABLE persons ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE person_history ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS persons_tenant_id ON persons (tenant_id);
+CREATE INDEX IF NOT EXISTS person_history_tenant_id ON person_history (tenant_id);
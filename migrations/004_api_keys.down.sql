@@ -0,0 +1,2 @@
+DROP INDEX api_keys_tenant_id;
+DROP TABLE api_keys;
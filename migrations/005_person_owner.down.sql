@@ -0,0 +1,2 @@
+ALTER TABLE person_history DROP COLUMN actor;
+ALTER TABLE persons DROP COLUMN owner;
@@ -0,0 +1,2 @@
+ALTER TABLE persons ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE person_history ADD COLUMN actor TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,22 @@
+-- name: InsertAPIKey :one
+-- store hashed api key, plaintext key is never persisted
+INSERT INTO api_keys (tenant_id, name, prefix, key_hash, scopes, expires_at)
+VALUES (
+    ?, ?, ?, ?, ?, ?
+) RETURNING *;
+
+-- name: ReadAPIKeyByHash :one
+SELECT *
+FROM api_keys
+WHERE key_hash = ?;
+
+-- name: ListAPIKeys :many
+SELECT *
+FROM api_keys
+WHERE tenant_id = ?
+ORDER BY id;
+
+-- name: RevokeAPIKey :execresult
+UPDATE api_keys
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = ? AND tenant_id = ? AND revoked_at IS NULL;
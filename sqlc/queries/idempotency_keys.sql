@@ -0,0 +1,36 @@
+-- name: ReserveIdempotencyKey :one
+-- claim key for an in flight request, returns no rows when key is already taken.
+-- reservations expire after a short lease so abandoned keys can be taken over
+INSERT INTO idempotency_keys (scope, idempotency_key, request_hash, expires_at)
+VALUES (
+    ?, ?, ?, ?
+)
+ON CONFLICT (scope, idempotency_key) DO NOTHING
+RETURNING *;
+
+-- name: ReadIdempotencyKey :one
+SELECT *
+FROM idempotency_keys
+WHERE scope = ? AND idempotency_key = ?;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET
+    status = ?,
+    content_type = ?,
+    body = ?,
+    expires_at = ?
+WHERE scope = ? AND idempotency_key = ?;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys WHERE scope = ? AND idempotency_key = ?;
+
+-- name: DeletePersonIdempotencyKeys :execresult
+-- drop stored responses of a tenant carrying the person, e.g. on erasure
+DELETE FROM idempotency_keys
+WHERE instr(scope, sqlc.arg('tenant_prefix')) = 1
+    AND json_valid(CAST(body AS TEXT))
+    AND json_extract(CAST(body AS TEXT), '$.id') = sqlc.arg('person_id');
+
+-- name: DeleteExpiredIdempotencyKeys :execresult
+DELETE FROM idempotency_keys WHERE expires_at <= ?;
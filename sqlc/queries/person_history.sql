@@ -0,0 +1,24 @@
+-- name: InsertPersonHistory :one
+-- append event to person audit trail
+INSERT INTO person_history (person_id, tenant_id, action, snapshot, actor)
+VALUES (
+    ?, ?, ?, ?, ?
+) RETURNING *;
+
+-- name: ListPersonHistory :many
+SELECT *
+FROM person_history
+WHERE person_id = ? AND tenant_id = ?
+ORDER BY id;
+
+-- name: ListPersonsHistory :many
+-- batch read of the audit trails of several persons
+SELECT *
+FROM person_history
+WHERE tenant_id = ? AND person_id IN (sqlc.slice('person_ids'))
+ORDER BY person_id, id;
+
+-- name: AnonymizePersonHistory :execresult
+UPDATE person_history
+SET snapshot = ?
+WHERE person_id = ? AND tenant_id = ? AND snapshot IS NOT NULL;
@@ -1,15 +1,42 @@
 
 -- name: InsertPerson :one
 -- add person into database
-INSERT INTO persons (fname, lname, email)
+INSERT INTO persons (fname, lname, email, tenant_id, owner)
 VALUES (
-    ?, ?, ?
+    ?, ?, ?, ?, ?
 ) RETURNING *;
 
 -- name: ReadPerson :one
 SELECT *
 FROM persons
-WHERE id = ?;
+WHERE id = ? AND tenant_id = ?;
+
+-- name: ReadPersons :many
+-- batch read of tenant persons by id
+SELECT *
+FROM persons
+WHERE tenant_id = ? AND id IN (sqlc.slice('ids'))
+ORDER BY id;
+
+-- name: ListPersons :many
+-- page through tenant persons ordered by id
+SELECT *
+FROM persons
+WHERE tenant_id = ? AND id > ?
+ORDER BY id
+LIMIT ?;
+
+-- name: SearchPersons :many
+-- page through tenant persons matching a pattern on name or email
+SELECT *
+FROM persons
+WHERE tenant_id = ? AND id > ? AND (
+    fname LIKE ? ESCAPE '\' OR
+    lname LIKE ? ESCAPE '\' OR
+    email LIKE ? ESCAPE '\'
+)
+ORDER BY id
+LIMIT ?;
 
 -- name: UpdatePerson :one
 UPDATE persons
@@ -18,8 +45,16 @@ SET
     lname = ?,
     email = ?,
     updated_at = CURRENT_TIMESTAMP
-WHERE id = ?
+WHERE id = ? AND tenant_id = ?
+RETURNING *;
+
+-- name: TransferPerson :one
+UPDATE persons
+SET
+    owner = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND tenant_id = ?
 RETURNING *;
 
 -- name: DeletePerson :execresult
-DELETE FROM persons WHERE id = ?;
\ No newline at end of file
+DELETE FROM persons WHERE id = ? AND tenant_id = ?;
\ No newline at end of file